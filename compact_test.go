@@ -0,0 +1,190 @@
+package sp_model
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/ajandera/sp_model/noSqlClientPredictedData"
+	"github.com/ajandera/sp_model/predictedData"
+	"github.com/ajandera/sp_model/rdbsClientInfo"
+)
+
+// memoryStore struct store predicted points in memory, points of keep measurement are not deleted to simulate failed delete
+type memoryStore struct {
+	buckets map[string][]predictedData.PredictedPoint
+	keep    string
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{buckets: map[string][]predictedData.PredictedPoint{}}
+}
+
+func (s *memoryStore) StoreData(measurement string, dayIndex string, value int, setAverageOrderAmount float64, time time.Time, bucket string, org string) (bool, error) {
+	return s.StoreRunData(predictedData.Run{}, measurement, dayIndex, value, setAverageOrderAmount, time, bucket, org)
+}
+
+func (s *memoryStore) StoreRunData(run predictedData.Run, measurement string, dayIndex string, value int, setAverageOrderAmount float64, time time.Time, bucket string, org string) (bool, error) {
+	return false, nil
+}
+
+func (s *memoryStore) Flush(bucket string, org string) (bool, error) {
+	return true, nil
+}
+
+// WritePoints function replace points of same measurement, day index, time and run like both backends
+func (s *memoryStore) WritePoints(points []predictedData.PredictedPoint, bucket string, org string) ([]predictedData.FailedPoint, error) {
+	for _, p := range points {
+		replaced := false
+		for i, e := range s.buckets[bucket] {
+			if e.Measurement == p.Measurement && e.DayIndex == p.DayIndex && e.Time.Equal(p.Time) && e.RunId == p.RunId {
+				s.buckets[bucket][i], replaced = p, true
+			}
+		}
+		if !replaced {
+			s.buckets[bucket] = append(s.buckets[bucket], p)
+		}
+	}
+	return nil, nil
+}
+
+func (s *memoryStore) GetPredictedPoints(bucket string, measurement string, from time.Time, to time.Time, org string) ([]predictedData.PredictedPoint, error) {
+	var result []predictedData.PredictedPoint
+	for _, p := range s.buckets[bucket] {
+		if (measurement == "" || p.Measurement == measurement) && !p.Time.Before(from) && p.Time.Before(to) {
+			result = append(result, p)
+		}
+	}
+	return result, nil
+}
+
+func (s *memoryStore) ProvisionBucket(bucket string, org string) error {
+	if _, ok := s.buckets[bucket]; !ok {
+		s.buckets[bucket] = nil
+	}
+	return nil
+}
+
+func (s *memoryStore) DeleteBucket(bucket string, org string) error {
+	delete(s.buckets, bucket)
+	return nil
+}
+
+func (s *memoryStore) ListBuckets(org string) ([]string, error) {
+	var result []string
+	for b := range s.buckets {
+		result = append(result, b)
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+func (s *memoryStore) DeletePoints(bucket string, measurement string, before time.Time, org string) error {
+	var kept []predictedData.PredictedPoint
+	for _, p := range s.buckets[bucket] {
+		if p.Measurement != measurement || !p.Time.Before(before) || measurement == s.keep {
+			kept = append(kept, p)
+		}
+	}
+	s.buckets[bucket] = kept
+	return nil
+}
+
+func (s *memoryStore) Close() {}
+
+// summary function return value of summary of measurement starting at start in bucket
+func (s *memoryStore) summary(t *testing.T, bucket string, measurement string, start time.Time) float64 {
+	for _, p := range s.buckets[bucket] {
+		if p.Measurement == measurement && p.Time.Equal(start) {
+			return p.Value
+		}
+	}
+	t.Fatalf("bucket %s has no %s summary at %v", bucket, measurement, start)
+	return 0
+}
+
+var (
+	// retention keeps 7 days, so compaction on wednesday 18th cuts off at monday 9th
+	compactNow    = time.Date(2023, 10, 18, 12, 0, 0, 0, time.UTC)
+	compactCutoff = time.Date(2023, 10, 9, 0, 0, 0, 0, time.UTC)
+	compactWeek   = time.Date(2023, 10, 2, 0, 0, 0, 0, time.UTC)
+)
+
+// compactPoints function return raw points around cutoff
+func compactPoints() []predictedData.PredictedPoint {
+	return []predictedData.PredictedPoint{
+		{Measurement: "orders", DayIndex: 1, Time: compactWeek, RunId: "a", Value: 10},
+		{Measurement: "orders", DayIndex: 1, Time: compactCutoff.Add(-time.Nanosecond), RunId: "a", Value: 5},
+		{Measurement: "orders", DayIndex: 1, Time: compactCutoff, RunId: "a", Value: 100},
+		{Measurement: "visitors", DayIndex: 1, Time: compactWeek.AddDate(0, 0, 1), RunId: "a", Value: 40},
+	}
+}
+
+func TestCompactCutoff(t *testing.T) {
+	for _, summaryBucket := range []string{"", "archive"} {
+		t.Run("summary bucket "+summaryBucket, func(t *testing.T) {
+			store := newMemoryStore()
+			store.WritePoints(compactPoints(), "store", "org")
+			r := Repository{predicted: store, org: "org"}
+			retention := rdbsClientInfo.PredictionRetentions{StoreRefer: "store", Period: noSqlClientPredictedData.PeriodWeek, KeepDays: 7, SummaryBucket: summaryBucket}
+
+			report, err := r.compact(retention, compactNow, false)
+			if err != nil {
+				t.Fatalf("compact: %v", err)
+			}
+			if !report.Cutoff.Equal(compactCutoff) || report.Points != 3 || report.Summaries != 2 || report.Deleted != 3 ||
+				!reflect.DeepEqual(report.Measurements, []string{"orders", "visitors"}) {
+				t.Errorf("report = %+v", report)
+			}
+			bucket := report.SummaryBucket
+			if got := store.summary(t, bucket, "orders_week", compactWeek); got != 15 {
+				t.Errorf("orders summary = %v, want 15", got)
+			}
+			// point at cutoff is kept raw
+			if raw, _ := store.GetPredictedPoints("store", "orders", compactCutoff, compactNow, "org"); len(raw) != 1 {
+				t.Errorf("store has %d raw points at cutoff, want 1", len(raw))
+			}
+
+			// run written after compaction replaces summarised day instead of being added to it
+			store.WritePoints([]predictedData.PredictedPoint{{Measurement: "orders", DayIndex: 1, Time: compactWeek, RunId: "b", Value: 20}}, "store", "org")
+			report, err = r.compact(retention, compactNow, false)
+			if err != nil {
+				t.Fatalf("compact: %v", err)
+			}
+			if report.Points != 1 || report.Deleted != 1 {
+				t.Errorf("late run report = %+v", report)
+			}
+			if got := store.summary(t, bucket, "orders_week", compactWeek); got != 25 {
+				t.Errorf("orders summary after late run = %v, want 25", got)
+			}
+			if got := store.summary(t, bucket, "visitors_week", compactWeek); got != 40 {
+				t.Errorf("visitors summary after late run = %v, want 40", got)
+			}
+		})
+	}
+}
+
+func TestCompactDryRun(t *testing.T) {
+	store := newMemoryStore()
+	store.WritePoints(compactPoints(), "store", "org")
+	r := Repository{predicted: store, org: "org"}
+	report, err := r.compact(rdbsClientInfo.PredictionRetentions{StoreRefer: "store", Period: noSqlClientPredictedData.PeriodWeek, KeepDays: 7}, compactNow, true)
+	if err != nil || report.Summaries != 2 || report.Deleted != 0 || len(store.buckets["store"]) != 4 {
+		t.Errorf("dry run report = %+v, %v with %d points left", report, err, len(store.buckets["store"]))
+	}
+}
+
+func TestCompactCountsDeletedPoints(t *testing.T) {
+	store := newMemoryStore()
+	store.keep = "visitors"
+	store.WritePoints(compactPoints(), "store", "org")
+	r := Repository{predicted: store, org: "org"}
+	report, err := r.compact(rdbsClientInfo.PredictionRetentions{StoreRefer: "store", Period: noSqlClientPredictedData.PeriodWeek, KeepDays: 7}, compactNow, false)
+	if err != nil {
+		t.Fatalf("compact: %v", err)
+	}
+	if report.Points != 3 || report.Deleted != 2 {
+		t.Errorf("report = %+v, want 3 points and 2 deleted", report)
+	}
+}
@@ -0,0 +1,136 @@
+package forecast
+
+import (
+	"time"
+
+	"github.com/ajandera/sp_model/rdbsClientData"
+)
+
+// BacktestConfig struct store candidate configuration of backtest
+type BacktestConfig struct {
+	Name    string
+	Weights Weights
+	Method  string
+}
+
+// BacktestOptions struct store rolling-origin setup
+// InitialTrain is number of days before first origin (four seasons by default), Step is days between origins (horizon by default),
+// MaxFolds keeps only last folds when positive
+type BacktestOptions struct {
+	Horizon      int
+	InitialTrain int
+	Step         int
+	MaxFolds     int
+}
+
+// Fold struct store forecast of one origin compared with actual values
+type Fold struct {
+	Origin   time.Time
+	Train    int
+	Forecast []float64
+	Actual   []float64
+	Metrics  rdbsClientData.ForecastAccuracy
+}
+
+// BacktestResult struct store folds of configuration and metrics over all forecast points
+type BacktestResult struct {
+	Config  BacktestConfig
+	Folds   []Fold
+	Metrics rdbsClientData.ForecastAccuracy
+}
+
+// initialTrain function return days before first origin, largest default of configurations when options have none
+func initialTrain(options BacktestOptions, configs ...BacktestConfig) int {
+	if options.InitialTrain > 0 {
+		return options.InitialTrain
+	}
+	initial := 0
+	for _, config := range configs {
+		if i := 4 * config.Weights.seasonLength(); i > initial {
+			initial = i
+		}
+	}
+	return initial
+}
+
+// origins function return indexes of series where folds start, none without horizon
+func origins(length int, initial int, options BacktestOptions) []int {
+	if options.Horizon <= 0 {
+		return nil
+	}
+	step := options.Step
+	if step <= 0 {
+		step = options.Horizon
+	}
+	var result []int
+	for o := initial; o+options.Horizon <= length; o += step {
+		result = append(result, o)
+	}
+	if options.MaxFolds > 0 && len(result) > options.MaxFolds {
+		result = result[len(result)-options.MaxFolds:]
+	}
+	return result
+}
+
+// Backtest function to replay daily series with rolling-origin cross-validation of configuration
+// each fold trains on days before origin, limited by History of weights, and forecasts horizon days after it
+func Backtest(series []rdbsClientData.ValueByDay, config BacktestConfig, options BacktestOptions) BacktestResult {
+	return backtest(series, config, options, origins(len(series), initialTrain(options, config), options))
+}
+
+// backtest function to replay series of configuration from given fold origins
+func backtest(series []rdbsClientData.ValueByDay, config BacktestConfig, options BacktestOptions, folds []int) BacktestResult {
+	result := BacktestResult{Config: config}
+	if options.Horizon <= 0 {
+		return result
+	}
+	values := make([]float64, len(series))
+	for i, v := range series {
+		values[i] = v.Value
+	}
+
+	var forecasts, actuals []float64
+	for _, o := range folds {
+		train := values[:o]
+		if config.Weights.History > 0 && len(train) > config.Weights.History {
+			train = train[len(train)-config.Weights.History:]
+		}
+		fold := Fold{
+			Origin:   series[o].Day,
+			Train:    len(train),
+			Forecast: Predict(train, config.Weights, options.Horizon, config.Method),
+			Actual:   values[o : o+options.Horizon],
+		}
+		fold.Metrics = rdbsClientData.Accuracy(fold.Forecast, fold.Actual)
+		result.Folds = append(result.Folds, fold)
+		forecasts = append(forecasts, fold.Forecast...)
+		actuals = append(actuals, fold.Actual...)
+	}
+	result.Metrics = rdbsClientData.Accuracy(forecasts, actuals)
+	return result
+}
+
+// Compare function to backtest configurations on same series and folds, results keep order of configurations
+// origins are shared, so default initial train is the largest one of configurations
+func Compare(series []rdbsClientData.ValueByDay, configs []BacktestConfig, options BacktestOptions) []BacktestResult {
+	var result []BacktestResult
+	folds := origins(len(series), initialTrain(options, configs...), options)
+	for _, config := range configs {
+		result = append(result, backtest(series, config, options, folds))
+	}
+	return result
+}
+
+// Best function return index of result with lowest RMSE, -1 when no result has folds
+func Best(results []BacktestResult) int {
+	best := -1
+	for i, r := range results {
+		if len(r.Folds) == 0 {
+			continue
+		}
+		if best < 0 || r.Metrics.RMSE < results[best].Metrics.RMSE {
+			best = i
+		}
+	}
+	return best
+}
@@ -0,0 +1,100 @@
+package forecast
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOrigins(t *testing.T) {
+	tests := []struct {
+		name    string
+		length  int
+		initial int
+		options BacktestOptions
+		want    []int
+	}{
+		{name: "step defaults to horizon", length: 20, initial: 10, options: BacktestOptions{Horizon: 3}, want: []int{10, 13, 16}},
+		{name: "step", length: 20, initial: 10, options: BacktestOptions{Horizon: 3, Step: 2}, want: []int{10, 12, 14, 16}},
+		{name: "last folds are kept", length: 20, initial: 10, options: BacktestOptions{Horizon: 3, Step: 2, MaxFolds: 2}, want: []int{14, 16}},
+		{name: "last fold ends with series", length: 13, initial: 10, options: BacktestOptions{Horizon: 3}, want: []int{10}},
+		{name: "series too short", length: 12, initial: 10, options: BacktestOptions{Horizon: 3}, want: nil},
+		{name: "no horizon", length: 20, initial: 10, options: BacktestOptions{}, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := origins(tt.length, tt.initial, tt.options); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("origins = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInitialTrain(t *testing.T) {
+	configs := []BacktestConfig{{Weights: Weights{SeasonLength: 3}}, {Weights: Weights{}}}
+	if got := initialTrain(BacktestOptions{}, configs...); got != 4*DefaultSeasonLength {
+		t.Errorf("initialTrain = %d, want largest default %d", got, 4*DefaultSeasonLength)
+	}
+	if got := initialTrain(BacktestOptions{InitialTrain: 5}, configs...); got != 5 {
+		t.Errorf("initialTrain = %d, want option 5", got)
+	}
+}
+
+func TestBacktestSeasonalSeries(t *testing.T) {
+	series := daily(repeat(week, 6))
+	result := Backtest(series, BacktestConfig{Name: "naive", Method: MethodSeasonalNaive}, BacktestOptions{Horizon: 7})
+	if len(result.Folds) != 2 {
+		t.Fatalf("Backtest returned %d folds, want 2", len(result.Folds))
+	}
+	for i, fold := range result.Folds {
+		origin := 28 + 7*i
+		if !fold.Origin.Equal(series[origin].Day) || fold.Train != origin {
+			t.Errorf("fold %d origin %v train %d, want %v and %d", i, fold.Origin, fold.Train, series[origin].Day, origin)
+		}
+		if !near(fold.Forecast, fold.Actual) || fold.Metrics.RMSE != 0 {
+			t.Errorf("fold %d forecast %v of actual %v has RMSE %v", i, fold.Forecast, fold.Actual, fold.Metrics.RMSE)
+		}
+	}
+	if result.Metrics.RMSE != 0 || result.Metrics.Count != 14 {
+		t.Errorf("Backtest metrics = %+v, want RMSE 0 over 14 points", result.Metrics)
+	}
+}
+
+func TestBacktestHistory(t *testing.T) {
+	result := Backtest(daily(repeat(week, 6)), BacktestConfig{Weights: Weights{History: 14}, Method: MethodSeasonalNaive}, BacktestOptions{Horizon: 7})
+	for _, fold := range result.Folds {
+		if fold.Train != 14 {
+			t.Errorf("fold at %v trained on %d days, want 14", fold.Origin, fold.Train)
+		}
+	}
+}
+
+func TestBacktestWithoutHorizon(t *testing.T) {
+	if result := Backtest(daily(repeat(week, 6)), BacktestConfig{}, BacktestOptions{}); len(result.Folds) != 0 {
+		t.Errorf("Backtest without horizon returned %d folds", len(result.Folds))
+	}
+}
+
+func TestCompareAndBest(t *testing.T) {
+	series := daily(repeat(week, 6))
+	configs := []BacktestConfig{
+		{Name: "last value", Weights: Weights{SeasonLength: 1}, Method: MethodSeasonalNaive},
+		{Name: "weekly", Method: MethodSeasonalNaive},
+	}
+	results := Compare(series, configs, BacktestOptions{Horizon: 7})
+	if len(results) != 2 {
+		t.Fatalf("Compare returned %d results", len(results))
+	}
+	// origins are shared, so last value config starts at default initial train of weekly config
+	if len(results[0].Folds) != len(results[1].Folds) || !results[0].Folds[0].Origin.Equal(results[1].Folds[0].Origin) {
+		t.Errorf("configurations have different folds %d and %d", len(results[0].Folds), len(results[1].Folds))
+	}
+	if results[0].Metrics.RMSE <= 0 || results[1].Metrics.RMSE != 0 {
+		t.Errorf("RMSE = %v and %v, want positive and 0", results[0].Metrics.RMSE, results[1].Metrics.RMSE)
+	}
+	if best := Best(results); best != 1 {
+		t.Errorf("Best = %d, want 1", best)
+	}
+	if best := Best([]BacktestResult{{}, {}}); best != -1 {
+		t.Errorf("Best of results without folds = %d, want -1", best)
+	}
+}
@@ -0,0 +1,210 @@
+// Package forecast to predict store series from StoreWeights
+package forecast
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/ajandera/sp_model/predictedData"
+	"github.com/ajandera/sp_model/rdbsClientData"
+	"github.com/ajandera/sp_model/rdbsClientInfo"
+)
+
+// Forecasting methods
+const (
+	MethodHoltWinters   = "holt-winters"
+	MethodSeasonalNaive = "seasonal-naive"
+	MethodBlend         = "blend"
+)
+
+// DefaultSeasonLength weekly season of daily series used when weights have no Shift
+const DefaultSeasonLength = 7
+
+// Weights struct store model parameters
+// Level, Trend and Season are Holt-Winters smoothing factors, SeasonLength and History are in days,
+// HoltWinters and Naive are weights of methods in blend
+type Weights struct {
+	Level        float64
+	Trend        float64
+	Season       float64
+	SeasonLength int
+	History      int
+	HoltWinters  float64
+	Naive        float64
+}
+
+// Point struct store forecast value of day, day index is number of series steps (days, weeks or months) after last observed day
+// intervals and quantiles are filled by ForecastWithBounds
+type Point struct {
+	Day       time.Time
+	DayIndex  int
+	Value     float64
+	Intervals []predictedData.Interval
+	Quantiles []predictedData.Quantile
+}
+
+// FromStoreWeights function return model weights from store weights
+// Beta, Gama and Delta smooth level, trend and season, Shift is season length, LongShift limits history,
+// A and B weight Holt-Winters and seasonal naive forecasts in blend, C, D, E and ProbabilityWeights are not used by these methods
+func FromStoreWeights(w rdbsClientInfo.StoreWeights) Weights {
+	return Weights{
+		Level:        w.Beta,
+		Trend:        w.Gama,
+		Season:       w.Delta,
+		SeasonLength: w.Shift,
+		History:      w.LongShift,
+		HoltWinters:  w.A,
+		Naive:        w.B,
+	}
+}
+
+// WeightsHash function return short hash of store weights parameters identifying configuration used by prediction run
+func WeightsHash(w rdbsClientInfo.StoreWeights) string {
+	sum := sha256.Sum256([]byte(fmt.Sprint(w.Beta, w.Gama, w.Delta, w.A, w.B, w.C, w.D, w.E, w.ProbabilityWeights, w.Shift, w.LongShift)))
+	return hex.EncodeToString(sum[:8])
+}
+
+// seasonLength function return season length of weights, weekly by default
+func (w Weights) seasonLength() int {
+	if w.SeasonLength > 0 {
+		return w.SeasonLength
+	}
+	return DefaultSeasonLength
+}
+
+// Values function return values of series limited to last History days of weights
+func Values(series []rdbsClientData.ValueByDay, w Weights) []float64 {
+	if w.History > 0 && len(series) > w.History {
+		series = series[len(series)-w.History:]
+	}
+	result := make([]float64, len(series))
+	for i, v := range series {
+		result[i] = v.Value
+	}
+	return result
+}
+
+// Forecast function return forecast points for horizon steps after last day of series
+// points follow granularity of series, so weekly and monthly series are forecast by weeks and months
+func Forecast(series []rdbsClientData.ValueByDay, w Weights, horizon int, method string) []Point {
+	var result []Point
+	if len(series) == 0 || horizon <= 0 {
+		return result
+	}
+	values := Predict(Values(series, w), w, horizon, method)
+	for h, v := range values {
+		result = append(result, Point{Day: next(series, h+1), DayIndex: h + 1, Value: v})
+	}
+	return result
+}
+
+// next function return day n steps after last day of series, step is distance of last two days, one day by default
+// month buckets have different lengths, so they are recognized and stepped by months
+func next(series []rdbsClientData.ValueByDay, n int) time.Time {
+	last := series[len(series)-1].Day
+	if len(series) < 2 {
+		return last.AddDate(0, 0, n)
+	}
+	previous := series[len(series)-2].Day
+	if previous.AddDate(0, 1, 0).Equal(last) {
+		return last.AddDate(0, n, 0)
+	}
+	step := last.Sub(previous)
+	if step%(24*time.Hour) == 0 {
+		return last.AddDate(0, 0, n*int(step/(24*time.Hour)))
+	}
+	return last.Add(time.Duration(n) * step)
+}
+
+// Predict function return horizon values following values by method, Holt-Winters by default
+func Predict(values []float64, w Weights, horizon int, method string) []float64 {
+	switch method {
+	case MethodSeasonalNaive:
+		return SeasonalNaive(values, w.seasonLength(), horizon)
+	case MethodBlend:
+		return Blend(values, w, horizon)
+	}
+	return HoltWinters(values, w, horizon)
+}
+
+// HoltWinters function return additive triple exponential smoothing forecast
+// series shorter than two seasons fall back to seasonal naive forecast, negative values are clipped to zero
+func HoltWinters(values []float64, w Weights, horizon int) []float64 {
+	m := w.seasonLength()
+	if len(values) < 2*m {
+		return SeasonalNaive(values, m, horizon)
+	}
+	alpha, beta, gamma := clamp(w.Level), clamp(w.Trend), clamp(w.Season)
+
+	level := mean(values[:m])
+	trend := (mean(values[m:2*m]) - level) / float64(m)
+	season := make([]float64, len(values)+horizon)
+	for i := 0; i < m; i++ {
+		season[i] = values[i] - level
+	}
+	for t := m; t < len(values); t++ {
+		previous := level
+		level = alpha*(values[t]-season[t-m]) + (1-alpha)*(level+trend)
+		trend = beta*(level-previous) + (1-beta)*trend
+		season[t] = gamma*(values[t]-level) + (1-gamma)*season[t-m]
+	}
+
+	result := make([]float64, horizon)
+	n := len(values)
+	for h := 1; h <= horizon; h++ {
+		s := season[n-m+(h-1)%m]
+		result[h-1] = math.Max(0, level+float64(h)*trend+s)
+	}
+	return result
+}
+
+// SeasonalNaive function return last observed season repeated over horizon
+func SeasonalNaive(values []float64, m int, horizon int) []float64 {
+	result := make([]float64, horizon)
+	if len(values) == 0 {
+		return result
+	}
+	if m <= 0 || m > len(values) {
+		m = len(values)
+	}
+	for h := 0; h < horizon; h++ {
+		result[h] = values[len(values)-m+h%m]
+	}
+	return result
+}
+
+// Blend function return Holt-Winters and seasonal naive forecasts weighted by HoltWinters and Naive weights
+// equal weights are used when both are zero
+func Blend(values []float64, w Weights, horizon int) []float64 {
+	a, b := w.HoltWinters, w.Naive
+	if a+b <= 0 {
+		a, b = 1, 1
+	}
+	hw := HoltWinters(values, w, horizon)
+	naive := SeasonalNaive(values, w.seasonLength(), horizon)
+	result := make([]float64, horizon)
+	for h := range result {
+		result[h] = (a*hw[h] + b*naive[h]) / (a + b)
+	}
+	return result
+}
+
+// clamp function return smoothing factor limited to interval 0 and 1
+func clamp(v float64) float64 {
+	return math.Min(1, math.Max(0, v))
+}
+
+// mean function return average of values
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
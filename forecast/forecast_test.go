@@ -0,0 +1,192 @@
+package forecast
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/ajandera/sp_model/rdbsClientData"
+)
+
+var firstDay = time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)
+
+// daily function return series of values on consecutive days
+func daily(values []float64) []rdbsClientData.ValueByDay {
+	series := make([]rdbsClientData.ValueByDay, len(values))
+	for i, v := range values {
+		series[i] = rdbsClientData.ValueByDay{Value: v, Day: firstDay.AddDate(0, 0, i)}
+	}
+	return series
+}
+
+// repeat function return pattern repeated count times
+func repeat(pattern []float64, count int) []float64 {
+	var values []float64
+	for i := 0; i < count; i++ {
+		values = append(values, pattern...)
+	}
+	return values
+}
+
+// near function return if values are equal within tolerance
+func near(got []float64, want []float64) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if math.Abs(got[i]-want[i]) > 1e-9 {
+			return false
+		}
+	}
+	return true
+}
+
+var week = []float64{1, 2, 3, 4, 5, 6, 7}
+
+func TestPredictConstantSeries(t *testing.T) {
+	values := repeat([]float64{10}, 28)
+	want := repeat([]float64{10}, 10)
+	w := Weights{Level: 0.3, Trend: 0.1, Season: 0.2}
+	for _, method := range []string{MethodHoltWinters, MethodSeasonalNaive, MethodBlend} {
+		if got := Predict(values, w, 10, method); !near(got, want) {
+			t.Errorf("Predict(%s) = %v, want %v", method, got, want)
+		}
+	}
+}
+
+func TestPredictSeasonalSeries(t *testing.T) {
+	values := repeat(week, 4)
+	want := append(append([]float64(nil), week...), week[:3]...)
+	for _, w := range []Weights{{Level: 0.3, Trend: 0.1, Season: 0.2}, {Level: 0.9, Trend: 0.9, Season: 0.9}, {Level: 0.01, Trend: 0, Season: 1}} {
+		for _, method := range []string{MethodHoltWinters, MethodSeasonalNaive, MethodBlend} {
+			if got := Predict(values, w, 10, method); !near(got, want) {
+				t.Errorf("Predict(%s, %+v) = %v, want %v", method, w, got, want)
+			}
+		}
+	}
+}
+
+func TestHoltWintersShortHistory(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		season int
+		want   []float64
+	}{
+		{name: "shorter than two seasons repeats last season", values: []float64{9, 9, 9, 1, 2, 3, 4, 5, 6, 7}, want: []float64{1, 2, 3, 4, 5, 6, 7, 1}},
+		{name: "shorter than season repeats whole history", values: []float64{4, 8}, want: []float64{4, 8, 4, 8, 4, 8, 4, 8}},
+		{name: "custom season length", values: []float64{1, 2, 3, 5, 6}, season: 3, want: []float64{3, 5, 6, 3, 5, 6, 3, 5}},
+		{name: "empty history", values: nil, want: make([]float64, 8)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HoltWinters(tt.values, Weights{Level: 0.5, SeasonLength: tt.season}, 8); !near(got, tt.want) {
+				t.Errorf("HoltWinters(%v) = %v, want %v", tt.values, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHoltWintersClipsNegativeValues(t *testing.T) {
+	values := []float64{70, 60, 50, 40, 30, 20, 10, 7, 6, 5, 4, 3, 2, 1}
+	for _, v := range HoltWinters(values, Weights{Level: 0.5, Trend: 0.5, Season: 0.5}, 14) {
+		if v < 0 {
+			t.Fatalf("HoltWinters returned negative value %v", v)
+		}
+	}
+}
+
+func TestBlendWeights(t *testing.T) {
+	values := []float64{9, 9, 9, 1, 2, 3, 4, 5, 6, 7}
+	tests := []struct {
+		name     string
+		hw, nv   float64
+		hwWeight float64
+	}{
+		{name: "weighted", hw: 1, nv: 3, hwWeight: 0.25},
+		{name: "zero weights are equal", hw: 0, nv: 0, hwWeight: 0.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := Weights{Level: 0.5, Trend: 0.2, Season: 0.3, SeasonLength: 3, HoltWinters: tt.hw, Naive: tt.nv}
+			hw, naive := HoltWinters(values, w, 5), SeasonalNaive(values, 3, 5)
+			want := make([]float64, 5)
+			for i := range want {
+				want[i] = tt.hwWeight*hw[i] + (1-tt.hwWeight)*naive[i]
+			}
+			if got := Blend(values, w, 5); !near(got, want) {
+				t.Errorf("Blend = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestValuesHistory(t *testing.T) {
+	series := daily(repeat(week, 2))
+	if got := Values(series, Weights{History: 3}); !near(got, []float64{5, 6, 7}) {
+		t.Errorf("Values with history = %v", got)
+	}
+	if got := Values(series, Weights{}); len(got) != 14 {
+		t.Errorf("Values without history returned %d values", len(got))
+	}
+}
+
+func TestForecastDays(t *testing.T) {
+	tests := []struct {
+		name   string
+		series []rdbsClientData.ValueByDay
+		want   []time.Time
+	}{
+		{name: "daily", series: daily([]float64{1, 2}), want: []time.Time{firstDay.AddDate(0, 0, 2), firstDay.AddDate(0, 0, 3)}},
+		{name: "single day", series: daily([]float64{1}), want: []time.Time{firstDay.AddDate(0, 0, 1), firstDay.AddDate(0, 0, 2)}},
+		{name: "weekly", series: []rdbsClientData.ValueByDay{{Value: 1, Day: firstDay}, {Value: 2, Day: firstDay.AddDate(0, 0, 7)}},
+			want: []time.Time{firstDay.AddDate(0, 0, 14), firstDay.AddDate(0, 0, 21)}},
+		{name: "monthly", series: []rdbsClientData.ValueByDay{{Value: 1, Day: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)}, {Value: 2, Day: time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC)}},
+			want: []time.Time{time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)}},
+		{name: "hourly", series: []rdbsClientData.ValueByDay{{Value: 1, Day: firstDay}, {Value: 2, Day: firstDay.Add(time.Hour)}},
+			want: []time.Time{firstDay.Add(2 * time.Hour), firstDay.Add(3 * time.Hour)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			points := Forecast(tt.series, Weights{}, 2, MethodSeasonalNaive)
+			if len(points) != len(tt.want) {
+				t.Fatalf("Forecast returned %d points, want %d", len(points), len(tt.want))
+			}
+			for i, p := range points {
+				if !p.Day.Equal(tt.want[i]) || p.DayIndex != i+1 {
+					t.Errorf("point %d = %v (index %d), want %v (index %d)", i, p.Day, p.DayIndex, tt.want[i], i+1)
+				}
+			}
+		})
+	}
+	if points := Forecast(nil, Weights{}, 2, MethodHoltWinters); len(points) != 0 {
+		t.Errorf("Forecast of empty series returned %d points", len(points))
+	}
+}
+
+func TestForecastWithBoundsShortHistory(t *testing.T) {
+	points := ForecastWithBounds(daily(week), Weights{}, 3, MethodHoltWinters, nil, []float64{0.5})
+	if len(points) != 3 {
+		t.Fatalf("ForecastWithBounds returned %d points", len(points))
+	}
+	for _, p := range points {
+		if len(p.Intervals) != 0 || len(p.Quantiles) != 0 {
+			t.Errorf("point %d of short series has bounds %+v %+v", p.DayIndex, p.Intervals, p.Quantiles)
+		}
+	}
+}
+
+func TestForecastWithBoundsSeasonalSeries(t *testing.T) {
+	// seasonal naive forecast of pure seasonal series has no errors, so bounds equal forecast value
+	points := ForecastWithBounds(daily(repeat(week, 8)), Weights{}, 7, MethodSeasonalNaive, nil, []float64{0.5})
+	for _, p := range points {
+		if len(p.Intervals) != 2 || len(p.Quantiles) != 1 {
+			t.Fatalf("point %d has bounds %+v %+v", p.DayIndex, p.Intervals, p.Quantiles)
+		}
+		for _, i := range p.Intervals {
+			if i.Lower != p.Value || i.Upper != p.Value {
+				t.Errorf("point %d interval %+v, want bounds %v", p.DayIndex, i, p.Value)
+			}
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package forecast
+
+import (
+	"math"
+	"sort"
+
+	"github.com/ajandera/sp_model/predictedData"
+	"github.com/ajandera/sp_model/rdbsClientData"
+)
+
+// Residual setup of intervals, residuals of last folds with daily step are used
+// and residuals of all day indexes are pooled when day index has fewer than intervalMinResiduals
+const (
+	intervalFolds        = 56
+	intervalMinResiduals = 8
+)
+
+// ForecastWithBounds function return forecast points with prediction intervals at levels and quantiles at probabilities
+// bounds are empirical quantiles of backtest errors added to forecast value and clipped to zero,
+// points have no bounds when series is too short for backtest, nil levels are predictedData.DefaultIntervalLevels
+func ForecastWithBounds(series []rdbsClientData.ValueByDay, w Weights, horizon int, method string, levels []float64, probabilities []float64) []Point {
+	if levels == nil {
+		levels = predictedData.DefaultIntervalLevels
+	}
+	points := Forecast(series, w, horizon, method)
+	if len(points) == 0 || len(levels)+len(probabilities) == 0 {
+		return points
+	}
+	residuals := Residuals(series, w, horizon, method)
+	for i := range points {
+		errors := residuals[points[i].DayIndex-1]
+		if len(errors) < intervalMinResiduals {
+			errors = pooled(residuals)
+		}
+		if len(errors) == 0 {
+			continue
+		}
+		sort.Float64s(errors)
+		value := points[i].Value
+		for _, l := range levels {
+			if l <= 0 || l >= 1 {
+				continue
+			}
+			points[i].Intervals = append(points[i].Intervals, predictedData.Interval{
+				Level: l,
+				Lower: math.Max(0, value+quantile(errors, (1-l)/2)),
+				Upper: math.Max(0, value+quantile(errors, (1+l)/2)),
+			})
+		}
+		for _, p := range probabilities {
+			if p <= 0 || p >= 1 {
+				continue
+			}
+			points[i].Quantiles = append(points[i].Quantiles, predictedData.Quantile{
+				Probability: p,
+				Value:       math.Max(0, value+quantile(errors, p)),
+			})
+		}
+	}
+	return points
+}
+
+// Residuals function return backtest errors, actual minus forecast, of each day index of horizon
+func Residuals(series []rdbsClientData.ValueByDay, w Weights, horizon int, method string) [][]float64 {
+	result := make([][]float64, horizon)
+	r := Backtest(series, BacktestConfig{Weights: w, Method: method}, BacktestOptions{Horizon: horizon, Step: 1, MaxFolds: intervalFolds})
+	for _, fold := range r.Folds {
+		for h := range fold.Forecast {
+			result[h] = append(result[h], fold.Actual[h]-fold.Forecast[h])
+		}
+	}
+	return result
+}
+
+// pooled function return residuals of all day indexes
+func pooled(residuals [][]float64) []float64 {
+	var result []float64
+	for _, r := range residuals {
+		result = append(result, r...)
+	}
+	return result
+}
+
+// quantile function return linearly interpolated quantile of sorted values at probability
+func quantile(sorted []float64, p float64) float64 {
+	position := p * float64(len(sorted)-1)
+	lower := int(math.Floor(position))
+	if lower >= len(sorted)-1 {
+		return sorted[len(sorted)-1]
+	}
+	return sorted[lower] + (position-float64(lower))*(sorted[lower+1]-sorted[lower])
+}
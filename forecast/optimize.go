@@ -0,0 +1,219 @@
+package forecast
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/ajandera/sp_model/rdbsClientData"
+	"github.com/ajandera/sp_model/rdbsClientInfo"
+)
+
+// Default optimiser setup
+const (
+	DefaultGridPoints    = 4
+	DefaultBudget        = 10 * time.Second
+	DefaultMaxIterations = 200
+)
+
+// Bound struct store closed interval of parameter
+type Bound struct {
+	Min float64
+	Max float64
+}
+
+// OptimizeOptions struct store optimiser setup
+// bounds of smoothing factors default to 0.01 and 0.99, HoltWinters bound is searched only for blend method
+// and Naive weight is its complement, SeasonLength and History of initial weights are kept
+type OptimizeOptions struct {
+	Backtest      BacktestOptions
+	Method        string
+	Level         Bound
+	Trend         Bound
+	Season        Bound
+	HoltWinters   Bound
+	GridPoints    int
+	Budget        time.Duration
+	MaxIterations int
+}
+
+// OptimizeResult struct store best weights with their backtest metrics
+type OptimizeResult struct {
+	Weights     Weights
+	Metrics     rdbsClientData.ForecastAccuracy
+	Evaluations int
+	Duration    time.Duration
+}
+
+// optimizer struct store search state
+type optimizer struct {
+	series   []rdbsClientData.ValueByDay
+	folds    []int
+	initial  Weights
+	options  OptimizeOptions
+	bounds   []Bound
+	deadline time.Time
+	result   OptimizeResult
+}
+
+// Optimize function to search weights with lowest backtest RMSE by grid search refined by Nelder-Mead
+// search stops after time budget, best weights found so far are returned
+func Optimize(series []rdbsClientData.ValueByDay, initial Weights, options OptimizeOptions) OptimizeResult {
+	start := time.Now()
+	if options.GridPoints <= 0 {
+		options.GridPoints = DefaultGridPoints
+	}
+	if options.Budget <= 0 {
+		options.Budget = DefaultBudget
+	}
+	if options.MaxIterations <= 0 {
+		options.MaxIterations = DefaultMaxIterations
+	}
+	// candidates keep season length of initial weights, so all of them and initial weights share origins
+	folds := origins(len(series), initialTrain(options.Backtest, BacktestConfig{Weights: initial}), options.Backtest)
+	o := optimizer{series: series, folds: folds, initial: initial, options: options, deadline: start.Add(options.Budget)}
+	o.result.Metrics.RMSE = math.Inf(1)
+	o.bounds = []Bound{bound(options.Level), bound(options.Trend), bound(options.Season)}
+	if options.Method == MethodBlend {
+		o.bounds = append(o.bounds, bound(options.HoltWinters))
+	}
+
+	best := o.grid()
+	if best != nil {
+		o.nelderMead(best)
+	}
+	o.result.Duration = time.Since(start)
+	return o.result
+}
+
+// bound function return bound limited to valid smoothing factors, default bound when empty
+func bound(b Bound) Bound {
+	if b.Max <= b.Min {
+		return Bound{0.01, 0.99}
+	}
+	return Bound{math.Max(0, b.Min), math.Min(1, b.Max)}
+}
+
+// weights function return weights of search point
+func (o *optimizer) weights(x []float64) Weights {
+	w := o.initial
+	w.Level, w.Trend, w.Season = x[0], x[1], x[2]
+	if len(x) > 3 {
+		w.HoltWinters, w.Naive = x[3], 1-x[3]
+	}
+	return w
+}
+
+// evaluate function return backtest RMSE of search point and keep best weights
+func (o *optimizer) evaluate(x []float64) float64 {
+	for i, b := range o.bounds {
+		x[i] = math.Min(b.Max, math.Max(b.Min, x[i]))
+	}
+	w := o.weights(x)
+	r := backtest(o.series, BacktestConfig{Weights: w, Method: o.options.Method}, o.options.Backtest, o.folds)
+	o.result.Evaluations++
+	if len(r.Folds) == 0 {
+		return math.Inf(1)
+	}
+	if r.Metrics.RMSE < o.result.Metrics.RMSE {
+		o.result.Weights = w
+		o.result.Metrics = r.Metrics
+	}
+	return r.Metrics.RMSE
+}
+
+// expired function return if time budget is spent
+func (o *optimizer) expired() bool {
+	return time.Now().After(o.deadline)
+}
+
+// grid function to evaluate regular grid inside bounds and return best point
+func (o *optimizer) grid() []float64 {
+	n := o.options.GridPoints
+	var best []float64
+	bestValue := math.Inf(1)
+	total := int(math.Pow(float64(n), float64(len(o.bounds))))
+	for k := 0; k < total && !o.expired(); k++ {
+		x := make([]float64, len(o.bounds))
+		index := k
+		for i, b := range o.bounds {
+			x[i] = b.Min + (b.Max-b.Min)*(float64(index%n)+0.5)/float64(n)
+			index /= n
+		}
+		if v := o.evaluate(x); v < bestValue {
+			best, bestValue = x, v
+		}
+	}
+	return best
+}
+
+// nelderMead function to refine point by Nelder-Mead simplex search
+func (o *optimizer) nelderMead(start []float64) {
+	dim := len(start)
+	type vertex struct {
+		x []float64
+		v float64
+	}
+	simplex := []vertex{{start, o.evaluate(append([]float64(nil), start...))}}
+	for i := 0; i < dim; i++ {
+		x := append([]float64(nil), start...)
+		x[i] += (o.bounds[i].Max - o.bounds[i].Min) / float64(2*o.options.GridPoints)
+		simplex = append(simplex, vertex{x, o.evaluate(x)})
+	}
+	point := func(c []float64, to []float64, t float64) []float64 {
+		x := make([]float64, dim)
+		for i := range x {
+			x[i] = c[i] + t*(to[i]-c[i])
+		}
+		return x
+	}
+
+	for iteration := 0; iteration < o.options.MaxIterations && !o.expired(); iteration++ {
+		sort.Slice(simplex, func(i, j int) bool { return simplex[i].v < simplex[j].v })
+		if simplex[dim].v-simplex[0].v < 1e-9 {
+			return
+		}
+		centroid := make([]float64, dim)
+		for _, s := range simplex[:dim] {
+			for i := range centroid {
+				centroid[i] += s.x[i] / float64(dim)
+			}
+		}
+		worst := simplex[dim]
+		reflected := point(centroid, worst.x, -1)
+		rv := o.evaluate(reflected)
+		switch {
+		case rv < simplex[0].v:
+			expanded := point(centroid, worst.x, -2)
+			if ev := o.evaluate(expanded); ev < rv {
+				simplex[dim] = vertex{expanded, ev}
+			} else {
+				simplex[dim] = vertex{reflected, rv}
+			}
+		case rv < simplex[dim-1].v:
+			simplex[dim] = vertex{reflected, rv}
+		default:
+			contracted := point(centroid, worst.x, 0.5)
+			if cv := o.evaluate(contracted); cv < worst.v {
+				simplex[dim] = vertex{contracted, cv}
+			} else {
+				for i := 1; i <= dim; i++ {
+					x := point(simplex[0].x, simplex[i].x, 0.5)
+					simplex[i] = vertex{x, o.evaluate(x)}
+				}
+			}
+		}
+	}
+}
+
+// ToStoreWeights function return store weights updated by model weights, other fields of store weights are kept
+func ToStoreWeights(w Weights, storeWeights rdbsClientInfo.StoreWeights) rdbsClientInfo.StoreWeights {
+	storeWeights.Beta = w.Level
+	storeWeights.Gama = w.Trend
+	storeWeights.Delta = w.Season
+	storeWeights.Shift = w.SeasonLength
+	storeWeights.LongShift = w.History
+	storeWeights.A = w.HoltWinters
+	storeWeights.B = w.Naive
+	return storeWeights
+}
@@ -0,0 +1,122 @@
+package forecast
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/ajandera/sp_model/rdbsClientInfo"
+)
+
+// trendSeries function return weekly seasonal values with linear trend
+func trendSeries(weeks int) []float64 {
+	var values []float64
+	for i := 0; i < 7*weeks; i++ {
+		values = append(values, 20+0.5*float64(i)+week[i%7])
+	}
+	return values
+}
+
+// budget is large enough for search to stop by iterations, so results do not depend on machine speed
+var testOptions = OptimizeOptions{Backtest: BacktestOptions{Horizon: 7}, GridPoints: 2, Budget: time.Minute, MaxIterations: 50}
+
+func TestOptimizeSeasonalSeries(t *testing.T) {
+	// every weights forecast pure seasonal series exactly, so grid finds its first point
+	// and Nelder-Mead stops after evaluating initial simplex
+	result := Optimize(daily(repeat(week, 6)), Weights{}, testOptions)
+	if result.Metrics.RMSE != 0 {
+		t.Errorf("Optimize RMSE = %v, want 0", result.Metrics.RMSE)
+	}
+	if result.Evaluations != 8+4 {
+		t.Errorf("Optimize made %d evaluations, want 8 grid and 4 simplex evaluations", result.Evaluations)
+	}
+	want := Weights{Level: 0.255, Trend: 0.255, Season: 0.255}
+	if math.Abs(result.Weights.Level-want.Level) > 1e-9 || math.Abs(result.Weights.Trend-want.Trend) > 1e-9 || math.Abs(result.Weights.Season-want.Season) > 1e-9 {
+		t.Errorf("Optimize weights = %+v, want first grid point %+v", result.Weights, want)
+	}
+}
+
+func TestOptimizeRefinesGrid(t *testing.T) {
+	series := daily(trendSeries(8))
+	options := testOptions
+	gridBest := math.Inf(1)
+	grid := []float64{0.255, 0.745}
+	for _, level := range grid {
+		for _, trend := range grid {
+			for _, season := range grid {
+				r := Backtest(series, BacktestConfig{Weights: Weights{Level: level, Trend: trend, Season: season}}, options.Backtest)
+				gridBest = math.Min(gridBest, r.Metrics.RMSE)
+			}
+		}
+	}
+
+	result := Optimize(series, Weights{}, options)
+	if result.Evaluations <= 8 {
+		t.Fatalf("Optimize made %d evaluations, Nelder-Mead did not run", result.Evaluations)
+	}
+	if result.Metrics.RMSE > gridBest {
+		t.Errorf("Optimize RMSE %v is worse than best grid RMSE %v", result.Metrics.RMSE, gridBest)
+	}
+	again := Optimize(series, Weights{}, options)
+	if again.Weights != result.Weights || again.Evaluations != result.Evaluations {
+		t.Errorf("Optimize is not deterministic: %+v after %+v", again.Weights, result.Weights)
+	}
+	for _, v := range []float64{result.Weights.Level, result.Weights.Trend, result.Weights.Season} {
+		if v < 0.01 || v > 0.99 {
+			t.Errorf("Optimize weights %+v are outside default bounds", result.Weights)
+		}
+	}
+}
+
+func TestOptimizeBlend(t *testing.T) {
+	options := testOptions
+	options.Method = MethodBlend
+	options.HoltWinters = Bound{0.2, 0.6}
+	initial := Weights{SeasonLength: 7, History: 35}
+	result := Optimize(daily(trendSeries(8)), initial, options)
+	if result.Evaluations <= 16 {
+		t.Errorf("Optimize made %d evaluations, want more than 16 grid evaluations", result.Evaluations)
+	}
+	w := result.Weights
+	if w.HoltWinters < 0.2 || w.HoltWinters > 0.6 || math.Abs(w.HoltWinters+w.Naive-1) > 1e-9 {
+		t.Errorf("blend weights %v and %v are outside bound or do not sum to 1", w.HoltWinters, w.Naive)
+	}
+	if w.SeasonLength != 7 || w.History != 35 {
+		t.Errorf("Optimize changed season length %d or history %d", w.SeasonLength, w.History)
+	}
+}
+
+func TestOptimizeShortSeries(t *testing.T) {
+	result := Optimize(daily(week), Weights{}, testOptions)
+	if !math.IsInf(result.Metrics.RMSE, 1) {
+		t.Errorf("Optimize of short series RMSE = %v, want +Inf", result.Metrics.RMSE)
+	}
+}
+
+func TestBound(t *testing.T) {
+	tests := []struct {
+		bound Bound
+		want  Bound
+	}{
+		{bound: Bound{}, want: Bound{0.01, 0.99}},
+		{bound: Bound{0.5, 0.5}, want: Bound{0.01, 0.99}},
+		{bound: Bound{-1, 2}, want: Bound{0, 1}},
+		{bound: Bound{0.2, 0.4}, want: Bound{0.2, 0.4}},
+	}
+	for _, tt := range tests {
+		if got := bound(tt.bound); got != tt.want {
+			t.Errorf("bound(%v) = %v, want %v", tt.bound, got, tt.want)
+		}
+	}
+}
+
+func TestStoreWeightsRoundTrip(t *testing.T) {
+	w := Weights{Level: 0.1, Trend: 0.2, Season: 0.3, SeasonLength: 7, History: 90, HoltWinters: 0.4, Naive: 0.6}
+	storeWeights := ToStoreWeights(w, rdbsClientInfo.StoreWeights{Name: "optimized", C: 5})
+	if got := FromStoreWeights(storeWeights); got != w {
+		t.Errorf("FromStoreWeights(ToStoreWeights(%+v)) = %+v", w, got)
+	}
+	if storeWeights.Name != "optimized" || storeWeights.C != 5 {
+		t.Errorf("ToStoreWeights changed other fields %+v", storeWeights)
+	}
+}
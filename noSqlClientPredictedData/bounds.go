@@ -0,0 +1,14 @@
+package noSqlClientPredictedData
+
+import "github.com/ajandera/sp_model/predictedData"
+
+// boundFields function return numeric bound fields of record values
+func boundFields(values map[string]interface{}) map[string]float64 {
+	fields := map[string]float64{}
+	for name, value := range values {
+		if value != nil && predictedData.IsBoundField(name) {
+			fields[name] = toFloat(value)
+		}
+	}
+	return fields
+}
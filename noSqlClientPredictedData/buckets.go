@@ -0,0 +1,158 @@
+package noSqlClientPredictedData
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/influxdb-client-go/v2/api"
+	"github.com/influxdata/influxdb-client-go/v2/domain"
+)
+
+// lookups struct cache organisation ids and buckets shared by copies of client
+type lookups struct {
+	sync.Mutex
+	orgs      map[string]string
+	buckets   map[string]*domain.Bucket
+	retention time.Duration
+}
+
+// newLookups function return empty cache
+func newLookups() *lookups {
+	return &lookups{orgs: map[string]string{}, buckets: map[string]*domain.Bucket{}}
+}
+
+// SetRetention function to set retention of buckets created for stores, zero keeps data forever
+func (client *ClientData) SetRetention(retention time.Duration) {
+	client.cache.Lock()
+	defer client.cache.Unlock()
+	client.cache.retention = retention
+}
+
+// orgId function return cached id of organisation
+func (client *ClientData) orgId(org string) (string, error) {
+	client.cache.Lock()
+	id, ok := client.cache.orgs[org]
+	client.cache.Unlock()
+	if ok {
+		return id, nil
+	}
+	o, err := client.db.OrganizationsAPI().FindOrganizationByName(context.Background(), org)
+	if err != nil {
+		return "", err
+	}
+	if o == nil || o.Id == nil {
+		return "", errors.New("organization " + org + " not found")
+	}
+	client.cache.Lock()
+	client.cache.orgs[org] = *o.Id
+	client.cache.Unlock()
+	return *o.Id, nil
+}
+
+// findBucket function return cached bucket, nil when bucket does not exist
+func (client *ClientData) findBucket(bucket string) *domain.Bucket {
+	client.cache.Lock()
+	b, ok := client.cache.buckets[bucket]
+	client.cache.Unlock()
+	if ok {
+		return b
+	}
+	b, err := client.db.BucketsAPI().FindBucketByName(context.Background(), bucket)
+	if err != nil || b == nil {
+		return nil
+	}
+	client.cache.Lock()
+	client.cache.buckets[bucket] = b
+	client.cache.Unlock()
+	return b
+}
+
+// retentionRules function return rules expiring data after retention
+func retentionRules(retention time.Duration) domain.RetentionRules {
+	expire := domain.RetentionRuleTypeExpire
+	return domain.RetentionRules{{EverySeconds: int64(retention / time.Second), Type: &expire}}
+}
+
+// ensureBucket function to create store bucket in organisation when it does not exist
+func (client *ClientData) ensureBucket(bucket string, org string) error {
+	if client.findBucket(bucket) != nil {
+		return nil
+	}
+	return client.ProvisionBucket(bucket, org)
+}
+
+// ProvisionBucket function to create store bucket with configured retention or update retention of existing bucket
+func (client *ClientData) ProvisionBucket(bucket string, org string) error {
+	client.cache.Lock()
+	rules := retentionRules(client.cache.retention)
+	client.cache.Unlock()
+
+	if b := client.findBucket(bucket); b != nil {
+		if len(b.RetentionRules) == 1 && b.RetentionRules[0].EverySeconds == rules[0].EverySeconds {
+			return nil
+		}
+		b.RetentionRules = rules
+		updated, err := client.db.BucketsAPI().UpdateBucket(context.Background(), b)
+		if err != nil {
+			return err
+		}
+		client.cache.Lock()
+		client.cache.buckets[bucket] = updated
+		client.cache.Unlock()
+		return nil
+	}
+
+	orgId, err := client.orgId(org)
+	if err != nil {
+		return err
+	}
+	b, err := client.db.BucketsAPI().CreateBucketWithNameWithID(context.Background(), orgId, bucket, rules...)
+	if err != nil {
+		return err
+	}
+	client.cache.Lock()
+	client.cache.buckets[bucket] = b
+	client.cache.Unlock()
+	return nil
+}
+
+// DeleteBucket function to delete store bucket with all its predicted data, missing bucket is not an error
+func (client *ClientData) DeleteBucket(bucket string, org string) error {
+	b := client.findBucket(bucket)
+	if b == nil || b.Id == nil {
+		return nil
+	}
+	if err := client.db.BucketsAPI().DeleteBucketWithID(context.Background(), *b.Id); err != nil {
+		return err
+	}
+	client.cache.Lock()
+	delete(client.cache.buckets, bucket)
+	client.cache.Unlock()
+	return nil
+}
+
+// ListBuckets function return names of buckets in organisation without system buckets
+func (client *ClientData) ListBuckets(org string) ([]string, error) {
+	var result []string
+	const limit = 100
+	for offset := 0; ; offset += limit {
+		buckets, err := client.db.BucketsAPI().FindBucketsByOrgName(context.Background(), org, api.PagingWithLimit(limit), api.PagingWithOffset(offset))
+		if err != nil {
+			return nil, err
+		}
+		if buckets == nil {
+			return result, nil
+		}
+		for _, b := range *buckets {
+			if !strings.HasPrefix(b.Name, "_") {
+				result = append(result, b.Name)
+			}
+		}
+		if len(*buckets) < limit {
+			return result, nil
+		}
+	}
+}
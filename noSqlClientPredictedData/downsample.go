@@ -0,0 +1,137 @@
+package noSqlClientPredictedData
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ajandera/sp_model/predictedData"
+)
+
+// Downsampling periods, summaries are written to measurement with period suffix, e.g. orders_week
+const (
+	PeriodWeek  = "week"
+	PeriodMonth = "month"
+)
+
+// PeriodStart function return start of week (monday) or month of time in UTC, day for other periods
+func PeriodStart(t time.Time, period string) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch period {
+	case PeriodWeek:
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	case PeriodMonth:
+		return day.AddDate(0, 0, 1-day.Day())
+	}
+	return day
+}
+
+// PeriodEnd function return start of period following period starting at start
+func PeriodEnd(start time.Time, period string) time.Time {
+	switch period {
+	case PeriodWeek:
+		return start.AddDate(0, 0, 7)
+	case PeriodMonth:
+		return start.AddDate(0, 1, 0)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+// SummaryMeasurement function return measurement of period summaries of measurement
+func SummaryMeasurement(measurement string, period string) string {
+	return measurement + "_" + period
+}
+
+// IsSummary function return if measurement stores period summaries
+func IsSummary(measurement string) bool {
+	return strings.HasSuffix(measurement, "_"+PeriodWeek) || strings.HasSuffix(measurement, "_"+PeriodMonth)
+}
+
+// Downsample function return period summaries of raw points, summary summarises latest run of each day
+// value is sum of daily values and saoa is their average, summary time is period start,
+// bounds are not kept, summary keeps value and saoa of each summarised day instead
+func Downsample(points []predictedData.PredictedPoint, period string) []predictedData.PredictedPoint {
+	return MergeSummaries(points, nil, period)
+}
+
+// MergeSummaries function return period summaries of raw points like Downsample merged with existing summaries of same period
+// day of raw points replaces contribution of same day in existing summary, so run written after period was summarised
+// replaces summarised run of its day instead of being added to it, existing summaries without raw points are not returned
+func MergeSummaries(points []predictedData.PredictedPoint, existing []predictedData.PredictedPoint, period string) []predictedData.PredictedPoint {
+	type day struct {
+		measurement string
+		dayIndex    int
+		day         int64
+	}
+	latest := map[day]predictedData.PredictedPoint{}
+	for _, p := range points {
+		if IsSummary(p.Measurement) {
+			continue
+		}
+		k := day{p.Measurement, p.DayIndex, PeriodStart(p.Time, "").Unix()}
+		if previous, ok := latest[k]; !ok || p.RunId >= previous.RunId {
+			latest[k] = p
+		}
+	}
+
+	type key struct {
+		measurement string
+		dayIndex    int
+		start       int64
+	}
+	days := map[key]map[int]predictedData.Contribution{}
+	for _, p := range latest {
+		start := PeriodStart(p.Time, period)
+		k := key{p.Measurement, p.DayIndex, start.Unix()}
+		if days[k] == nil {
+			days[k] = map[int]predictedData.Contribution{}
+		}
+		offset := int(PeriodStart(p.Time, "").Sub(start).Hours() / 24)
+		days[k][offset] = predictedData.Contribution{Day: offset, Value: p.Value, AverageOrderAmount: p.AverageOrderAmount}
+	}
+	for _, e := range existing {
+		k := key{strings.TrimSuffix(e.Measurement, "_"+period), e.DayIndex, e.Time.Unix()}
+		if e.Measurement == k.measurement || days[k] == nil {
+			continue
+		}
+		for _, d := range e.Days {
+			if _, ok := days[k][d.Day]; !ok {
+				days[k][d.Day] = d
+			}
+		}
+	}
+
+	var result []predictedData.PredictedPoint
+	for k, contributions := range days {
+		s := predictedData.PredictedPoint{Measurement: SummaryMeasurement(k.measurement, period), DayIndex: k.dayIndex, Time: time.Unix(k.start, 0).UTC()}
+		for _, d := range contributions {
+			s.Days = append(s.Days, d)
+		}
+		sort.Slice(s.Days, func(i, j int) bool { return s.Days[i].Day < s.Days[j].Day })
+		for _, d := range s.Days {
+			s.Value += d.Value
+			s.AverageOrderAmount += d.AverageOrderAmount
+		}
+		s.AverageOrderAmount /= float64(len(s.Days))
+		result = append(result, s)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].Time.Equal(result[j].Time) {
+			return result[i].Time.Before(result[j].Time)
+		}
+		if result[i].Measurement != result[j].Measurement {
+			return result[i].Measurement < result[j].Measurement
+		}
+		return result[i].DayIndex < result[j].DayIndex
+	})
+	return result
+}
+
+// DeletePoints function to delete points of measurement in store bucket older than before
+// influx delete stop is inclusive, so points at before are kept by stopping one nanosecond earlier
+func (client *ClientData) DeletePoints(bucket string, measurement string, before time.Time, org string) error {
+	return client.db.DeleteAPI().DeleteWithName(context.Background(), org, bucket, time.Unix(0, 0), before.Add(-time.Nanosecond),
+		"_measurement="+quote(measurement))
+}
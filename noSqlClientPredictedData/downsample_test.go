@@ -0,0 +1,129 @@
+package noSqlClientPredictedData
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ajandera/sp_model/predictedData"
+)
+
+// monday start of week summarised by tests
+var monday = time.Date(2023, 10, 2, 0, 0, 0, 0, time.UTC)
+
+// raw function return raw orders point of day after monday
+func raw(day int, run string, value float64, saoa float64) predictedData.PredictedPoint {
+	return predictedData.PredictedPoint{Measurement: "orders", DayIndex: 1, Time: monday.AddDate(0, 0, day).Add(time.Hour), RunId: run, Value: value, AverageOrderAmount: saoa}
+}
+
+func TestPeriodStart(t *testing.T) {
+	tests := []struct {
+		time   time.Time
+		period string
+		want   time.Time
+	}{
+		{time: monday, period: PeriodWeek, want: monday},
+		{time: monday.Add(-time.Nanosecond), period: PeriodWeek, want: monday.AddDate(0, 0, -7)},
+		{time: monday.AddDate(0, 0, 6).Add(23 * time.Hour), period: PeriodWeek, want: monday},
+		{time: time.Date(2023, 10, 31, 23, 0, 0, 0, time.UTC), period: PeriodMonth, want: time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC)},
+		{time: time.Date(2023, 10, 2, 1, 0, 0, 0, time.FixedZone("CEST", 2*3600)), period: PeriodWeek, want: monday.AddDate(0, 0, -7)},
+		{time: monday.Add(5 * time.Hour), period: "", want: monday},
+	}
+	for _, tt := range tests {
+		if got := PeriodStart(tt.time, tt.period); !got.Equal(tt.want) {
+			t.Errorf("PeriodStart(%v, %q) = %v, want %v", tt.time, tt.period, got, tt.want)
+		}
+	}
+	if end := PeriodEnd(time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC), PeriodMonth); !end.Equal(time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("PeriodEnd of february = %v", end)
+	}
+	if end := PeriodEnd(monday, PeriodWeek); !end.Equal(monday.AddDate(0, 0, 7)) {
+		t.Errorf("PeriodEnd of week = %v", end)
+	}
+}
+
+func TestDownsample(t *testing.T) {
+	points := []predictedData.PredictedPoint{
+		raw(0, "a", 10, 2),
+		raw(0, "b", 12, 4), // latest run of day replaces run a
+		raw(1, "a", 8, 6),
+		raw(7, "a", 5, 1), // next week
+		{Measurement: "orders_week", DayIndex: 1, Time: monday, Value: 100},
+	}
+	got := Downsample(points, PeriodWeek)
+	want := []predictedData.PredictedPoint{
+		{Measurement: "orders_week", DayIndex: 1, Time: monday, Value: 20, AverageOrderAmount: 5,
+			Days: []predictedData.Contribution{{Day: 0, Value: 12, AverageOrderAmount: 4}, {Day: 1, Value: 8, AverageOrderAmount: 6}}},
+		{Measurement: "orders_week", DayIndex: 1, Time: monday.AddDate(0, 0, 7), Value: 5, AverageOrderAmount: 1,
+			Days: []predictedData.Contribution{{Day: 0, Value: 5, AverageOrderAmount: 1}}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Downsample = %+v, want %+v", got, want)
+	}
+}
+
+func TestDownsampleMonth(t *testing.T) {
+	// last days of september and october
+	got := Downsample([]predictedData.PredictedPoint{raw(-2, "a", 3, 0), raw(29, "a", 4, 0)}, PeriodMonth)
+	if len(got) != 2 || got[0].Measurement != "orders_month" || got[0].Days[0].Day != 29 || got[1].Days[0].Day != 30 {
+		t.Errorf("Downsample by month = %+v", got)
+	}
+}
+
+func TestMergeSummaries(t *testing.T) {
+	existing := Downsample([]predictedData.PredictedPoint{raw(0, "a", 10, 2), raw(1, "a", 8, 6), raw(2, "a", 4, 4)}, PeriodWeek)
+	other := predictedData.PredictedPoint{Measurement: "orders_week", DayIndex: 1, Time: monday.AddDate(0, 0, -7), Value: 50,
+		Days: []predictedData.Contribution{{Day: 0, Value: 50}}}
+	existing = append(existing, other)
+
+	tests := []struct {
+		name   string
+		points []predictedData.PredictedPoint
+		value  float64
+		saoa   float64
+		days   int
+	}{
+		{name: "late run replaces its day", points: []predictedData.PredictedPoint{raw(1, "b", 20, 9)}, value: 34, saoa: 5, days: 3},
+		{name: "late point adds uncovered day", points: []predictedData.PredictedPoint{raw(3, "b", 6, 8)}, value: 28, saoa: 5, days: 4},
+		{name: "same run merged again is not counted twice", points: []predictedData.PredictedPoint{raw(0, "a", 10, 2), raw(2, "a", 4, 4)}, value: 22, saoa: 4, days: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := MergeSummaries(tt.points, existing, PeriodWeek)
+			if len(got) != 1 {
+				t.Fatalf("MergeSummaries returned %d summaries, want only summary of raw points period", len(got))
+			}
+			s := got[0]
+			if !s.Time.Equal(monday) || s.Value != tt.value || math.Abs(s.AverageOrderAmount-tt.saoa) > 1e-9 || len(s.Days) != tt.days {
+				t.Errorf("summary = %+v, want value %v, saoa %v and %d days", s, tt.value, tt.saoa, tt.days)
+			}
+		})
+	}
+
+	if got := MergeSummaries(nil, existing, PeriodWeek); len(got) != 0 {
+		t.Errorf("MergeSummaries without raw points returned %+v", got)
+	}
+}
+
+func TestMergeSummariesIgnoresOtherPeriod(t *testing.T) {
+	existing := []predictedData.PredictedPoint{{Measurement: "orders_month", DayIndex: 1, Time: monday, Value: 100,
+		Days: []predictedData.Contribution{{Day: 5, Value: 100}}}}
+	got := MergeSummaries([]predictedData.PredictedPoint{raw(0, "a", 1, 0)}, existing, PeriodWeek)
+	if len(got) != 1 || got[0].Value != 1 {
+		t.Errorf("MergeSummaries merged summary of other period: %+v", got)
+	}
+}
+
+func TestSummaryDaysRoundTrip(t *testing.T) {
+	summary := Downsample([]predictedData.PredictedPoint{raw(0, "a", 10, 2.5), raw(6, "a", 3, 1)}, PeriodWeek)[0]
+	line := lineProtocol(summary)
+	if got, err := parseLine(line); err != nil || !reflect.DeepEqual(got.Days, summary.Days) {
+		t.Errorf("parseLine(%q) days = %+v, %v, want %+v", line, got.Days, err, summary.Days)
+	}
+	record := []string{summary.Measurement, summary.Time.Format(time.RFC3339Nano), "1", float(summary.Value), float(summary.AverageOrderAmount),
+		"", "", "", "", `{"day_0":10,"saoa_0":2.5,"day_6":3,"saoa_6":1}`}
+	if got, err := parseRecord(record); err != nil || !reflect.DeepEqual(got.Days, summary.Days) {
+		t.Errorf("parseRecord days = %+v, %v, want %+v", got.Days, err, summary.Days)
+	}
+}
@@ -3,14 +3,18 @@ package noSqlClientPredictedData
 
 import (
 	"context"
+	"strconv"
+	"time"
+
+	"github.com/ajandera/sp_model/predictedData"
 	"github.com/influxdata/influxdb-client-go/v2"
 	"github.com/influxdata/influxdb-client-go/v2/api"
-	"time"
 )
 
 // ClientData struct to store influx client
 type ClientData struct {
-	db influxdb2.Client
+	db    influxdb2.Client
+	cache *lookups
 }
 
 // NewConnect function to connect to influx
@@ -19,28 +23,37 @@ func NewConnect(url string, token string) ClientData {
 	if Client == nil {
 		panic("failed to connect influxdb")
 	}
-	return ClientData{Client}
+	return ClientData{Client, newLookups()}
 }
 
 // StoreData function to store data in influx bucket
-// bucket is a store id
+// bucket is a store id, point is written asynchronously, so write errors are returned by Flush
 func (client *ClientData) StoreData(measurement string, dayIndex string, value int,
 	setAverageOrderAmount float64, time time.Time, bucket string, org string) (bool, error) {
-	buck, err := client.db.BucketsAPI().FindBucketByName(context.Background(), bucket)
-	o, err := client.db.OrganizationsAPI().FindOrganizationByName(context.Background(), org)
-	if err != nil || buck == nil {
-		buck, err = client.db.BucketsAPI().CreateBucketWithNameWithID(context.Background(), *o.Id, bucket)
-		if err != nil {
-			return false, err
-		}
+	return client.StoreRunData(predictedData.Run{}, measurement, dayIndex, value, setAverageOrderAmount, time, bucket, org)
+}
+
+// StoreRunData function to store data in influx bucket like StoreData, point is tagged by run when run has id
+func (client *ClientData) StoreRunData(run predictedData.Run, measurement string, dayIndex string, value int,
+	setAverageOrderAmount float64, time time.Time, bucket string, org string) (bool, error) {
+	if err := client.ensureBucket(bucket, org); err != nil {
+		return false, err
 	}
 
 	writeAPI := client.db.WriteAPI(org, bucket)
+	// errors are collected only when channel is read before writes
+	writeAPI.Errors()
 	p := influxdb2.NewPointWithMeasurement(measurement).
 		AddTag("daysToMeasurement", dayIndex).
 		AddField("value", value).
 		AddField("saoa", setAverageOrderAmount).
 		SetTime(time)
+	if run.Id != "" {
+		p.AddTag("run", run.Id).
+			AddTag("model", run.Model).
+			AddTag("modelVersion", run.ModelVersion).
+			AddTag("weightsHash", run.WeightsHash)
+	}
 
 	// write point immediately
 	writeAPI.WritePoint(p)
@@ -48,6 +61,7 @@ func (client *ClientData) StoreData(measurement string, dayIndex string, value i
 }
 
 // Flush function to flush data for bucket
+// returns first error of asynchronous writes since last flush, other errors of same flush are dropped by influx client
 func (client *ClientData) Flush(bucket string, org string) (bool, error) {
 
 	writeAPI := client.db.WriteAPI(org, bucket)
@@ -55,7 +69,12 @@ func (client *ClientData) Flush(bucket string, org string) (bool, error) {
 	// Force all unwritten data to be sent
 	writeAPI.Flush()
 
-	return true, nil
+	select {
+	case err := <-writeAPI.Errors():
+		return false, err
+	default:
+		return true, nil
+	}
 }
 
 // GetData function to get predicted data by query
@@ -67,9 +86,6 @@ func (client *ClientData) GetData(query string, org string) (string, error) {
 	// Use default dialect
 	result, err := queryAPI.QueryRaw(context.Background(), query, influxdb2.DefaultDialect())
 
-	// Ensures background processes finishes
-	client.db.Close()
-
 	return result, err
 }
 
@@ -82,8 +98,77 @@ func (client *ClientData) GetQuery(query string, org string) (*api.QueryTableRes
 	// get QueryTableResult
 	result, err := queryAPI.Query(context.Background(), query)
 
-	// Ensures background processes finishes
+	return result, err
+}
+
+// GetPredictedPoints function return predicted points of store bucket between from and to
+// empty measurement returns points of all measurements
+func (client *ClientData) GetPredictedPoints(bucket string, measurement string, from time.Time, to time.Time, org string) ([]predictedData.PredictedPoint, error) {
+	query := NewQuery(bucket).Range(from, to)
+	if measurement != "" {
+		query.Measurement(measurement)
+	}
+	return client.GetPredictedPointsByQuery(query.Pivot(), org)
+}
+
+// GetPredictedPointsByQuery function return predicted points selected by query, query should be pivoted to read fields
+func (client *ClientData) GetPredictedPointsByQuery(query *Query, org string) ([]predictedData.PredictedPoint, error) {
+	flux, err := query.Build()
+	if err != nil {
+		return nil, err
+	}
+	result, err := client.db.QueryAPI(org).Query(context.Background(), flux)
+	if err != nil {
+		return nil, err
+	}
+	defer result.Close()
+
+	var points []predictedData.PredictedPoint
+	for result.Next() {
+		record := result.Record()
+		dayIndex, _ := strconv.Atoi(toString(record.ValueByKey("daysToMeasurement")))
+		bounds := boundFields(record.Values())
+		intervals, quantiles := predictedData.ParseBounds(bounds)
+		points = append(points, predictedData.PredictedPoint{
+			Measurement:        record.Measurement(),
+			DayIndex:           dayIndex,
+			Value:              toFloat(record.ValueByKey("value")),
+			AverageOrderAmount: toFloat(record.ValueByKey("saoa")),
+			Time:               record.Time(),
+			RunId:              toString(record.ValueByKey("run")),
+			Model:              toString(record.ValueByKey("model")),
+			ModelVersion:       toString(record.ValueByKey("modelVersion")),
+			WeightsHash:        toString(record.ValueByKey("weightsHash")),
+			Intervals:          intervals,
+			Quantiles:          quantiles,
+			Days:               predictedData.ParseDays(bounds),
+		})
+	}
+	return points, result.Err()
+}
+
+// Close function to close influx client, call it once when application stops using the client
+func (client *ClientData) Close() {
 	client.db.Close()
+}
 
-	return result, err
+// toString function return string value of record column
+func toString(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return ""
+}
+
+// toFloat function return numeric value of record column as float
+func toFloat(v interface{}) float64 {
+	switch n := v.(type) {
+	case float64:
+		return n
+	case int64:
+		return float64(n)
+	case uint64:
+		return float64(n)
+	}
+	return 0
 }
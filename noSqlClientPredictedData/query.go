@@ -0,0 +1,169 @@
+package noSqlClientPredictedData
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Aggregation functions allowed in query window
+var windowFunctions = map[string]bool{
+	"mean": true, "median": true, "sum": true, "count": true, "min": true, "max": true, "first": true, "last": true,
+}
+
+// Query struct to build flux query of predicted data, values are escaped when query is built
+type Query struct {
+	bucket       string
+	start        string
+	stop         string
+	measurements []string
+	dayIndexes   []string
+	fields       []string
+	runs         []string
+	every        time.Duration
+	fn           string
+	last         bool
+	pivot        bool
+	err          error
+}
+
+// NewQuery function return query of store bucket
+func NewQuery(bucket string) *Query {
+	return &Query{bucket: bucket}
+}
+
+// Range function to limit query to points between start and stop
+func (q *Query) Range(start time.Time, stop time.Time) *Query {
+	q.start = start.UTC().Format(time.RFC3339Nano)
+	q.stop = stop.UTC().Format(time.RFC3339Nano)
+	return q
+}
+
+// RangeRelative function to limit query to points from start duration relative to now, e.g. -30 days
+func (q *Query) RangeRelative(start time.Duration) *Query {
+	q.start = duration(start)
+	q.stop = ""
+	return q
+}
+
+// Measurement function to filter points by measurements
+func (q *Query) Measurement(names ...string) *Query {
+	q.measurements = append(q.measurements, names...)
+	return q
+}
+
+// DayIndex function to filter points by daysToMeasurement tag
+func (q *Query) DayIndex(indexes ...int) *Query {
+	for _, i := range indexes {
+		q.dayIndexes = append(q.dayIndexes, strconv.Itoa(i))
+	}
+	return q
+}
+
+// Field function to filter points by fields, value and saoa are stored by StoreData, bounds like lower_0.95 or q_0.5 by WritePoints
+func (q *Query) Field(names ...string) *Query {
+	q.fields = append(q.fields, names...)
+	return q
+}
+
+// Run function to filter points by run ids
+func (q *Query) Run(ids ...string) *Query {
+	q.runs = append(q.runs, ids...)
+	return q
+}
+
+// Window function to aggregate points in windows of duration by function like mean or sum
+func (q *Query) Window(every time.Duration, fn string) *Query {
+	if !windowFunctions[fn] {
+		q.err = errors.New("unsupported window function " + fn)
+	}
+	if every <= 0 {
+		q.err = errors.New("window duration must be positive")
+	}
+	q.every = every
+	q.fn = fn
+	return q
+}
+
+// Last function to return only last point of each series
+func (q *Query) Last() *Query {
+	q.last = true
+	return q
+}
+
+// Pivot function to return fields of point as columns of one row
+func (q *Query) Pivot() *Query {
+	q.pivot = true
+	return q
+}
+
+// Build function return flux query or error of invalid query
+func (q *Query) Build() (string, error) {
+	if q.err != nil {
+		return "", q.err
+	}
+	if q.bucket == "" {
+		return "", errors.New("bucket is required")
+	}
+	if q.start == "" {
+		return "", errors.New("range is required")
+	}
+
+	query := "from(bucket: " + quote(q.bucket) + ") |> range(start: " + q.start
+	if q.stop != "" {
+		query += ", stop: " + q.stop
+	}
+	query += ")"
+	query += filter("r._measurement", q.measurements)
+	query += filter("r.daysToMeasurement", q.dayIndexes)
+	query += filter("r._field", q.fields)
+	query += filter("r.run", q.runs)
+	if q.fn != "" {
+		query += " |> aggregateWindow(every: " + duration(q.every) + ", fn: " + q.fn + ", createEmpty: false)"
+	}
+	if q.last {
+		query += " |> last()"
+	}
+	if q.pivot {
+		// measurement and tags stay in group key of each table, so they are kept as columns
+		query += " |> pivot(rowKey: [\"_time\"], columnKey: [\"_field\"], valueColumn: \"_value\")"
+	}
+	return query, nil
+}
+
+// String function return flux query, empty for invalid query
+func (q *Query) String() string {
+	query, _ := q.Build()
+	return query
+}
+
+// filter function return flux filter of column equal to any of values
+func filter(column string, values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	var conditions []string
+	for _, v := range values {
+		conditions = append(conditions, column+" == "+quote(v))
+	}
+	return " |> filter(fn: (r) => " + strings.Join(conditions, " or ") + ")"
+}
+
+// duration function return flux duration literal in largest exact unit
+func duration(d time.Duration) string {
+	for _, unit := range []struct {
+		size time.Duration
+		name string
+	}{{time.Hour, "h"}, {time.Minute, "m"}, {time.Second, "s"}} {
+		if d%unit.size == 0 {
+			return strconv.FormatInt(int64(d/unit.size), 10) + unit.name
+		}
+	}
+	return strconv.FormatInt(d.Nanoseconds(), 10) + "ns"
+}
+
+// quote function return flux string literal of value
+func quote(value string) string {
+	return "\"" + strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "${", "\\${").Replace(value) + "\""
+}
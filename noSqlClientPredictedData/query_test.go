@@ -0,0 +1,86 @@
+package noSqlClientPredictedData
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{name: "plain", value: "orders", want: `"orders"`},
+		{name: "quote", value: `say "hi"`, want: `"say \"hi\""`},
+		{name: "backslash", value: `a\b`, want: `"a\\b"`},
+		{name: "backslash before quote", value: `a\"`, want: `"a\\\""`},
+		{name: "interpolation", value: "${bucket}", want: `"\${bucket}"`},
+		{name: "regex metacharacters are literal", value: `.*+?^$()[]{}|/`, want: `".*+?^$()[]{}|/"`},
+		{name: "empty", value: "", want: `""`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := quote(tt.value); got != tt.want {
+				t.Errorf("quote(%q) = %s, want %s", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryEscaping(t *testing.T) {
+	start := time.Date(2023, 10, 2, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		query *Query
+		want  string
+	}{
+		{name: "measurement with quote and backslash", query: NewQuery("store").Range(start, start.AddDate(0, 0, 1)).Measurement(`or"ders\`),
+			want: `from(bucket: "store") |> range(start: 2023-10-02T00:00:00Z, stop: 2023-10-03T00:00:00Z) |> filter(fn: (r) => r._measurement == "or\"ders\\")`},
+		{name: "measurement with regex metacharacters", query: NewQuery("store").RangeRelative(-24*time.Hour).Measurement("orders.*", "visitors|orders"),
+			want: `from(bucket: "store") |> range(start: -24h) |> filter(fn: (r) => r._measurement == "orders.*" or r._measurement == "visitors|orders")`},
+		{name: "run tag with quote and interpolation", query: NewQuery("store").RangeRelative(-time.Hour).Run(`a") or (r) => true or ("`, "${x}"),
+			want: `from(bucket: "store") |> range(start: -1h) |> filter(fn: (r) => r.run == "a\") or (r) => true or (\"" or r.run == "\${x}")`},
+		{name: "bucket with quote", query: NewQuery(`st"ore`).RangeRelative(-time.Minute).Field("lower_0.95"),
+			want: `from(bucket: "st\"ore") |> range(start: -1m) |> filter(fn: (r) => r._field == "lower_0.95")`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.query.Build()
+			if err != nil {
+				t.Fatalf("Build: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Build() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryBuild(t *testing.T) {
+	query, err := NewQuery("store").RangeRelative(-30*24*time.Hour).Measurement("orders").DayIndex(1, 7).Window(90*time.Second, "mean").Last().Pivot().Build()
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	for _, want := range []string{`range(start: -720h)`, `r.daysToMeasurement == "1" or r.daysToMeasurement == "7"`,
+		`aggregateWindow(every: 90s, fn: mean, createEmpty: false)`, `|> last() |> pivot(`} {
+		if !strings.Contains(query, want) {
+			t.Errorf("query %s does not contain %s", query, want)
+		}
+	}
+
+	for name, q := range map[string]*Query{
+		"missing bucket":  NewQuery("").RangeRelative(-time.Hour),
+		"missing range":   NewQuery("store"),
+		"window function": NewQuery("store").RangeRelative(-time.Hour).Window(time.Hour, "mean) |> drop("),
+		"window duration": NewQuery("store").RangeRelative(-time.Hour).Window(0, "sum"),
+	} {
+		if _, err := q.Build(); err == nil {
+			t.Errorf("%s: Build returned no error", name)
+		}
+		if q.String() != "" {
+			t.Errorf("%s: String() = %q, want empty", name, q.String())
+		}
+	}
+}
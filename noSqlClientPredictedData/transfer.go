@@ -0,0 +1,263 @@
+package noSqlClientPredictedData
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ajandera/sp_model/predictedData"
+)
+
+// Export formats of predicted points
+const (
+	FormatLineProtocol = "line"
+	FormatCSV          = "csv"
+)
+
+// csvHeader columns of csv export, bounds column store json of bound fields
+var csvHeader = []string{"measurement", "time", "day_index", "value", "saoa", "run", "model", "model_version", "weights_hash", "bounds"}
+
+// Encode function to write points in format and return number of written points
+// line protocol matches points written by WritePoints with nanosecond timestamps
+func Encode(w io.Writer, points []predictedData.PredictedPoint, format string) (int, error) {
+	switch format {
+	case FormatLineProtocol:
+		writer := bufio.NewWriter(w)
+		for i, p := range points {
+			if _, err := writer.WriteString(lineProtocol(p) + "\n"); err != nil {
+				return i, err
+			}
+		}
+		return len(points), writer.Flush()
+	case FormatCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write(csvHeader); err != nil {
+			return 0, err
+		}
+		for i, p := range points {
+			bounds, _ := json.Marshal(p.BoundFields())
+			record := []string{p.Measurement, p.Time.UTC().Format(time.RFC3339Nano), strconv.Itoa(p.DayIndex), float(p.Value), float(p.AverageOrderAmount),
+				p.RunId, p.Model, p.ModelVersion, p.WeightsHash, string(bounds)}
+			if err := writer.Write(record); err != nil {
+				return i, err
+			}
+		}
+		writer.Flush()
+		return len(points), writer.Error()
+	}
+	return 0, errors.New("unsupported format " + format)
+}
+
+// Decode function return points read in format, error names line of invalid point
+func Decode(r io.Reader, format string) ([]predictedData.PredictedPoint, error) {
+	var points []predictedData.PredictedPoint
+	switch format {
+	case FormatLineProtocol:
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for line := 1; scanner.Scan(); line++ {
+			text := strings.TrimSpace(scanner.Text())
+			if text == "" || strings.HasPrefix(text, "#") {
+				continue
+			}
+			p, err := parseLine(text)
+			if err != nil {
+				return nil, errors.New("line " + strconv.Itoa(line) + ": " + err.Error())
+			}
+			points = append(points, p)
+		}
+		return points, scanner.Err()
+	case FormatCSV:
+		reader := csv.NewReader(r)
+		reader.FieldsPerRecord = len(csvHeader)
+		records, err := reader.ReadAll()
+		if err != nil {
+			return nil, err
+		}
+		for i, record := range records {
+			if i == 0 && record[0] == csvHeader[0] {
+				continue
+			}
+			p, err := parseRecord(record)
+			if err != nil {
+				return nil, errors.New("line " + strconv.Itoa(i+1) + ": " + err.Error())
+			}
+			points = append(points, p)
+		}
+		return points, nil
+	}
+	return nil, errors.New("unsupported format " + format)
+}
+
+// lineProtocol function return line of point with tags and fields written by WritePoints
+func lineProtocol(p predictedData.PredictedPoint) string {
+	var sb strings.Builder
+	sb.WriteString(escape(p.Measurement, ", "))
+	tags := [][2]string{{"daysToMeasurement", strconv.Itoa(p.DayIndex)}}
+	if p.RunId != "" {
+		tags = append(tags, [2]string{"model", p.Model}, [2]string{"modelVersion", p.ModelVersion}, [2]string{"run", p.RunId}, [2]string{"weightsHash", p.WeightsHash})
+	}
+	for _, t := range tags {
+		if t[1] != "" {
+			sb.WriteString("," + escape(t[0], ",= ") + "=" + escape(t[1], ",= "))
+		}
+	}
+	sb.WriteString(" saoa=" + float(p.AverageOrderAmount) + ",value=" + strconv.FormatInt(int64(math.Round(p.Value)), 10) + "i")
+	bounds := p.BoundFields()
+	var names []string
+	for name := range bounds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		sb.WriteString("," + escape(name, ",= ") + "=" + float(bounds[name]))
+	}
+	sb.WriteString(" " + strconv.FormatInt(p.Time.UnixNano(), 10))
+	return sb.String()
+}
+
+// parseLine function return point of line protocol line, string fields and unknown tags are ignored
+func parseLine(line string) (predictedData.PredictedPoint, error) {
+	sections := split(line, ' ')
+	if len(sections) != 3 {
+		return predictedData.PredictedPoint{}, errors.New("expected measurement with tags, fields and timestamp")
+	}
+	keys := split(sections[0], ',')
+	p := predictedData.PredictedPoint{Measurement: unescape(keys[0])}
+	for _, tag := range keys[1:] {
+		pair := split(tag, '=')
+		if len(pair) != 2 {
+			return p, errors.New("invalid tag " + tag)
+		}
+		value := unescape(pair[1])
+		switch unescape(pair[0]) {
+		case "daysToMeasurement":
+			index, err := strconv.Atoi(value)
+			if err != nil {
+				return p, err
+			}
+			p.DayIndex = index
+		case "run":
+			p.RunId = value
+		case "model":
+			p.Model = value
+		case "modelVersion":
+			p.ModelVersion = value
+		case "weightsHash":
+			p.WeightsHash = value
+		}
+	}
+
+	bounds := map[string]float64{}
+	for _, field := range split(sections[1], ',') {
+		pair := split(field, '=')
+		if len(pair) != 2 {
+			return p, errors.New("invalid field " + field)
+		}
+		if strings.HasPrefix(pair[1], "\"") {
+			continue
+		}
+		value, err := strconv.ParseFloat(strings.TrimRight(pair[1], "iu"), 64)
+		if err != nil {
+			return p, errors.New("invalid field " + field)
+		}
+		switch name := unescape(pair[0]); name {
+		case "value":
+			p.Value = value
+		case "saoa":
+			p.AverageOrderAmount = value
+		default:
+			bounds[name] = value
+		}
+	}
+	p.Intervals, p.Quantiles = predictedData.ParseBounds(bounds)
+	p.Days = predictedData.ParseDays(bounds)
+
+	nanos, err := strconv.ParseInt(sections[2], 10, 64)
+	if err != nil {
+		return p, errors.New("invalid timestamp " + sections[2])
+	}
+	p.Time = time.Unix(0, nanos).UTC()
+	return p, nil
+}
+
+// parseRecord function return point of csv record
+func parseRecord(record []string) (predictedData.PredictedPoint, error) {
+	p := predictedData.PredictedPoint{Measurement: record[0], RunId: record[5], Model: record[6], ModelVersion: record[7], WeightsHash: record[8]}
+	var err error
+	if p.Time, err = time.Parse(time.RFC3339Nano, record[1]); err != nil {
+		return p, err
+	}
+	if p.DayIndex, err = strconv.Atoi(record[2]); err != nil {
+		return p, err
+	}
+	if p.Value, err = strconv.ParseFloat(record[3], 64); err != nil {
+		return p, err
+	}
+	if p.AverageOrderAmount, err = strconv.ParseFloat(record[4], 64); err != nil {
+		return p, err
+	}
+	if record[9] != "" {
+		var bounds map[string]float64
+		if err = json.Unmarshal([]byte(record[9]), &bounds); err != nil {
+			return p, err
+		}
+		p.Intervals, p.Quantiles = predictedData.ParseBounds(bounds)
+		p.Days = predictedData.ParseDays(bounds)
+	}
+	return p, nil
+}
+
+// split function return parts of text separated by separator which is not escaped or quoted
+func split(text string, separator byte) []string {
+	var parts []string
+	start, quoted := 0, false
+	for i := 0; i < len(text); i++ {
+		switch {
+		case text[i] == '\\':
+			i++
+		case text[i] == '"':
+			quoted = !quoted
+		case text[i] == separator && !quoted:
+			parts = append(parts, text[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, text[start:])
+}
+
+// escape function return text with backslash before each of special characters
+func escape(text string, special string) string {
+	var sb strings.Builder
+	for _, r := range text {
+		if strings.ContainsRune(special, r) {
+			sb.WriteRune('\\')
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// unescape function return text without backslashes escaping special characters
+func unescape(text string) string {
+	var sb strings.Builder
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\\' && i+1 < len(text) && strings.IndexByte(",= \"", text[i+1]) >= 0 {
+			i++
+		}
+		sb.WriteByte(text[i])
+	}
+	return sb.String()
+}
+
+// float function return shortest decimal of value
+func float(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
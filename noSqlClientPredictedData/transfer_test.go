@@ -0,0 +1,143 @@
+package noSqlClientPredictedData
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ajandera/sp_model/predictedData"
+)
+
+func transferPoints() []predictedData.PredictedPoint {
+	day := time.Date(2023, 10, 2, 0, 0, 0, 123456789, time.UTC)
+	return []predictedData.PredictedPoint{
+		{Measurement: "orders", DayIndex: 1, Value: 42, AverageOrderAmount: 12.5, Time: day},
+		{Measurement: "orders, week=1", DayIndex: 7, Value: 3, AverageOrderAmount: 0.25, Time: day.AddDate(0, 0, 7),
+			RunId: "20231002T000000Z-ab,cd", Model: "holt winters", ModelVersion: "v=1", WeightsHash: "a b,c=d",
+			Intervals: []predictedData.Interval{{Level: 0.8, Lower: 1.5, Upper: 4.5}, {Level: 0.95, Lower: 0, Upper: 6.25}},
+			Quantiles: []predictedData.Quantile{{Probability: 0.5, Value: 3}}},
+	}
+}
+
+func TestLineProtocolRoundTrip(t *testing.T) {
+	for _, p := range transferPoints() {
+		line := lineProtocol(p)
+		got, err := parseLine(line)
+		if err != nil {
+			t.Fatalf("parseLine(%q): %v", line, err)
+		}
+		if !reflect.DeepEqual(got, p) {
+			t.Errorf("parseLine(%q) = %+v, want %+v", line, got, p)
+		}
+	}
+}
+
+func TestLineProtocolEscaping(t *testing.T) {
+	line := lineProtocol(transferPoints()[1])
+	for _, want := range []string{`orders\,\ week=1,`, `model=holt\ winters`, `modelVersion=v\=1`, `run=20231002T000000Z-ab\,cd`, `weightsHash=a\ b\,c\=d`, ",value=3i,"} {
+		if !strings.Contains(line, want) {
+			t.Errorf("line %q does not contain %q", line, want)
+		}
+	}
+}
+
+func TestParseLine(t *testing.T) {
+	tests := []struct {
+		name  string
+		line  string
+		want  predictedData.PredictedPoint
+		error bool
+	}{
+		{name: "integer and unsigned fields", line: "visitors,daysToMeasurement=2 value=5i,saoa=7u 1696204800000000000",
+			want: predictedData.PredictedPoint{Measurement: "visitors", DayIndex: 2, Value: 5, AverageOrderAmount: 7, Time: time.Unix(1696204800, 0).UTC()}},
+		{name: "string fields and unknown tags are ignored", line: `visitors,daysToMeasurement=1,host=a value=1i,note="a b,c=d" 0`,
+			want: predictedData.PredictedPoint{Measurement: "visitors", DayIndex: 1, Value: 1, Time: time.Unix(0, 0).UTC()}},
+		{name: "interval needs both bounds", line: "visitors,daysToMeasurement=1 value=1i,lower_0.9=1,q_0.1=0.5 0",
+			want: predictedData.PredictedPoint{Measurement: "visitors", DayIndex: 1, Value: 1, Time: time.Unix(0, 0).UTC(),
+				Quantiles: []predictedData.Quantile{{Probability: 0.1, Value: 0.5}}}},
+		{name: "missing timestamp", line: "visitors,daysToMeasurement=1 value=1i", error: true},
+		{name: "invalid day index", line: "visitors,daysToMeasurement=x value=1i 0", error: true},
+		{name: "invalid tag", line: "visitors,daysToMeasurement value=1i 0", error: true},
+		{name: "invalid field", line: "visitors,daysToMeasurement=1 value=x 0", error: true},
+		{name: "invalid timestamp", line: "visitors,daysToMeasurement=1 value=1i now", error: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseLine(tt.line)
+			if tt.error {
+				if err == nil {
+					t.Errorf("parseLine(%q) returned no error", tt.line)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseLine(%q): %v", tt.line, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseLine(%q) = %+v, want %+v", tt.line, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRecord(t *testing.T) {
+	tests := []struct {
+		name   string
+		record []string
+		error  bool
+	}{
+		{name: "invalid time", record: []string{"orders", "2023-10-02", "1", "1", "0", "", "", "", "", ""}, error: true},
+		{name: "invalid day index", record: []string{"orders", "2023-10-02T00:00:00Z", "x", "1", "0", "", "", "", "", ""}, error: true},
+		{name: "invalid value", record: []string{"orders", "2023-10-02T00:00:00Z", "1", "x", "0", "", "", "", "", ""}, error: true},
+		{name: "invalid saoa", record: []string{"orders", "2023-10-02T00:00:00Z", "1", "1", "x", "", "", "", "", ""}, error: true},
+		{name: "invalid bounds", record: []string{"orders", "2023-10-02T00:00:00Z", "1", "1", "0", "", "", "", "", "{"}, error: true},
+		{name: "empty bounds", record: []string{"orders", "2023-10-02T00:00:00Z", "1", "1", "0", "", "", "", "", ""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseRecord(tt.record)
+			if (err != nil) != tt.error {
+				t.Errorf("parseRecord(%q) error = %v, want error %v", tt.record, err, tt.error)
+			}
+		})
+	}
+}
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	points := transferPoints()
+	points[0].Value = 41.75
+	for _, format := range []string{FormatLineProtocol, FormatCSV} {
+		var buffer bytes.Buffer
+		n, err := Encode(&buffer, points, format)
+		if err != nil || n != len(points) {
+			t.Fatalf("Encode(%s) = %d, %v", format, n, err)
+		}
+		got, err := Decode(&buffer, format)
+		if err != nil {
+			t.Fatalf("Decode(%s): %v", format, err)
+		}
+		want := transferPoints()
+		want[0].Value = 41.75
+		if format == FormatLineProtocol {
+			// value is written as integer like by WritePoints
+			want[0].Value = 42
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Decode(%s) = %+v, want %+v", format, got, want)
+		}
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	if _, err := Decode(strings.NewReader("orders 0"), FormatLineProtocol); err == nil || !strings.HasPrefix(err.Error(), "line 1:") {
+		t.Errorf("Decode of invalid line returned %v", err)
+	}
+	if _, err := Decode(strings.NewReader(""), "json"); err == nil {
+		t.Error("Decode of unsupported format returned no error")
+	}
+	if _, err := Encode(&bytes.Buffer{}, nil, "json"); err == nil {
+		t.Error("Encode of unsupported format returned no error")
+	}
+}
@@ -0,0 +1,110 @@
+package noSqlClientPredictedData
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/ajandera/sp_model/predictedData"
+	"github.com/influxdata/influxdb-client-go/v2"
+	"github.com/influxdata/influxdb-client-go/v2/api/http"
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
+)
+
+// Batch write setup, failed transient writes are retried with doubled backoff
+var (
+	WriteRetries = 3
+	WriteBackoff = 200 * time.Millisecond
+)
+
+// WritePoints function to write points to store bucket with blocking api and return points which were not written
+// error is nil only when all points are persisted, value is written as integer like in StoreData,
+// interval bounds and quantiles are written as float fields like lower_0.95, upper_0.95 or q_0.5
+func (client *ClientData) WritePoints(points []predictedData.PredictedPoint, bucket string, org string) ([]predictedData.FailedPoint, error) {
+	if err := client.ensureBucket(bucket, org); err != nil {
+		return failAll(points, err), err
+	}
+	writeAPI := client.db.WriteAPIBlocking(org, bucket)
+
+	var failed []predictedData.FailedPoint
+	for start := 0; start < len(points); start += predictedData.WriteBatchSize {
+		end := start + predictedData.WriteBatchSize
+		if end > len(points) {
+			end = len(points)
+		}
+		batch := points[start:end]
+		if err := writeWithRetry(writeAPI.WritePoint, toInfluxPoints(batch)); err != nil {
+			// retries are used up for transient error, writing points one by one would only repeat it
+			if transient(err) {
+				failed = append(failed, failAll(batch, err)...)
+				continue
+			}
+			// write points of rejected batch one by one to find the failing ones
+			for _, p := range batch {
+				if err := writeWithRetry(writeAPI.WritePoint, toInfluxPoints([]predictedData.PredictedPoint{p})); err != nil {
+					failed = append(failed, predictedData.FailedPoint{Point: p, Err: err})
+				}
+			}
+		}
+	}
+	return failed, predictedData.WriteError(failed, len(points))
+}
+
+// writeWithRetry function to call write and retry transient failures with backoff
+func writeWithRetry(writePoint func(context.Context, ...*write.Point) error, points []*write.Point) error {
+	backoff := WriteBackoff
+	var err error
+	for attempt := 0; attempt <= WriteRetries; attempt++ {
+		if err = writePoint(context.Background(), points...); err == nil || !transient(err) {
+			return err
+		}
+		if attempt < WriteRetries {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+	return err
+}
+
+// transient function return if write error may succeed when repeated, network errors, throttling and server errors
+func transient(err error) bool {
+	var httpError *http.Error
+	if errors.As(err, &httpError) {
+		return httpError.StatusCode == 0 || httpError.StatusCode == 429 || httpError.StatusCode >= 500
+	}
+	return true
+}
+
+// toInfluxPoints function return influx points of predicted points
+func toInfluxPoints(points []predictedData.PredictedPoint) []*write.Point {
+	result := make([]*write.Point, len(points))
+	for i, p := range points {
+		point := influxdb2.NewPointWithMeasurement(p.Measurement).
+			AddTag("daysToMeasurement", strconv.Itoa(p.DayIndex)).
+			AddField("value", int(math.Round(p.Value))).
+			AddField("saoa", p.AverageOrderAmount).
+			SetTime(p.Time)
+		for name, value := range p.BoundFields() {
+			point.AddField(name, value)
+		}
+		if p.RunId != "" {
+			point.AddTag("run", p.RunId).
+				AddTag("model", p.Model).
+				AddTag("modelVersion", p.ModelVersion).
+				AddTag("weightsHash", p.WeightsHash)
+		}
+		result[i] = point
+	}
+	return result
+}
+
+// failAll function return all points as failed with error
+func failAll(points []predictedData.PredictedPoint, err error) []predictedData.FailedPoint {
+	failed := make([]predictedData.FailedPoint, len(points))
+	for i, p := range points {
+		failed[i] = predictedData.FailedPoint{Point: p, Err: err}
+	}
+	return failed
+}
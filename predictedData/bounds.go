@@ -0,0 +1,137 @@
+package predictedData
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultIntervalLevels confidence levels of prediction intervals used when no levels are given
+var DefaultIntervalLevels = []float64{0.8, 0.95}
+
+// Field prefixes of bounds, level or probability follows prefix, e.g. lower_0.95 or q_0.5
+// day of summary contribution follows day and saoa prefixes, e.g. day_3 and saoa_3
+const (
+	lowerPrefix    = "lower_"
+	upperPrefix    = "upper_"
+	quantilePrefix = "q_"
+	dayPrefix      = "day_"
+	saoaPrefix     = "saoa_"
+)
+
+// Interval struct store lower and upper bound of prediction interval at confidence level between 0 and 1
+type Interval struct {
+	Level float64
+	Lower float64
+	Upper float64
+}
+
+// Quantile struct store predicted value at probability between 0 and 1
+type Quantile struct {
+	Probability float64
+	Value       float64
+}
+
+// Contribution struct store value and saoa of one day summarised by period summary, day is offset from period start
+type Contribution struct {
+	Day                int
+	Value              float64
+	AverageOrderAmount float64
+}
+
+// Interval function return prediction interval of point at confidence level
+func (p PredictedPoint) Interval(level float64) (Interval, bool) {
+	for _, i := range p.Intervals {
+		if i.Level == level {
+			return i, true
+		}
+	}
+	return Interval{}, false
+}
+
+// Quantile function return predicted value of point at probability
+func (p PredictedPoint) Quantile(probability float64) (float64, bool) {
+	for _, q := range p.Quantiles {
+		if q.Probability == probability {
+			return q.Value, true
+		}
+	}
+	return 0, false
+}
+
+// BoundFields function return fields of point intervals, quantiles and summary days
+// levels and probabilities outside 0 and 1 are skipped
+func (p PredictedPoint) BoundFields() map[string]float64 {
+	fields := map[string]float64{}
+	for _, i := range p.Intervals {
+		if valid(i.Level) {
+			fields[lowerPrefix+level(i.Level)] = i.Lower
+			fields[upperPrefix+level(i.Level)] = i.Upper
+		}
+	}
+	for _, q := range p.Quantiles {
+		if valid(q.Probability) {
+			fields[quantilePrefix+level(q.Probability)] = q.Value
+		}
+	}
+	for _, d := range p.Days {
+		fields[dayPrefix+strconv.Itoa(d.Day)] = d.Value
+		fields[saoaPrefix+strconv.Itoa(d.Day)] = d.AverageOrderAmount
+	}
+	return fields
+}
+
+// ParseBounds function return intervals and quantiles of bound fields ordered by level and probability
+// other fields are ignored, interval is returned only when both its bounds are present
+func ParseBounds(fields map[string]float64) ([]Interval, []Quantile) {
+	var intervals []Interval
+	var quantiles []Quantile
+	for name, value := range fields {
+		switch {
+		case strings.HasPrefix(name, lowerPrefix):
+			l, err := strconv.ParseFloat(strings.TrimPrefix(name, lowerPrefix), 64)
+			upper, ok := fields[upperPrefix+strings.TrimPrefix(name, lowerPrefix)]
+			if err == nil && ok {
+				intervals = append(intervals, Interval{Level: l, Lower: value, Upper: upper})
+			}
+		case strings.HasPrefix(name, quantilePrefix):
+			if p, err := strconv.ParseFloat(strings.TrimPrefix(name, quantilePrefix), 64); err == nil {
+				quantiles = append(quantiles, Quantile{Probability: p, Value: value})
+			}
+		}
+	}
+	sort.Slice(intervals, func(i, j int) bool { return intervals[i].Level < intervals[j].Level })
+	sort.Slice(quantiles, func(i, j int) bool { return quantiles[i].Probability < quantiles[j].Probability })
+	return intervals, quantiles
+}
+
+// ParseDays function return summary days of bound fields ordered by day, day without saoa field has zero saoa
+func ParseDays(fields map[string]float64) []Contribution {
+	var days []Contribution
+	for name, value := range fields {
+		if !strings.HasPrefix(name, dayPrefix) {
+			continue
+		}
+		if d, err := strconv.Atoi(strings.TrimPrefix(name, dayPrefix)); err == nil {
+			days = append(days, Contribution{Day: d, Value: value, AverageOrderAmount: fields[saoaPrefix+strconv.Itoa(d)]})
+		}
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Day < days[j].Day })
+	return days
+}
+
+// IsBoundField function return if field name is interval bound, quantile or summary day field
+func IsBoundField(name string) bool {
+	return strings.HasPrefix(name, lowerPrefix) || strings.HasPrefix(name, upperPrefix) || strings.HasPrefix(name, quantilePrefix) ||
+		strings.HasPrefix(name, dayPrefix) || strings.HasPrefix(name, saoaPrefix)
+}
+
+// level function return field suffix of level or probability
+func level(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// valid function return if level or probability is inside interval 0 and 1
+func valid(v float64) bool {
+	return v > 0 && v < 1
+}
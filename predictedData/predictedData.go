@@ -0,0 +1,43 @@
+// Package predictedData to share predicted points between influx and postgres prediction backends
+package predictedData
+
+import (
+	"errors"
+	"strconv"
+	"time"
+)
+
+// WriteBatchSize number of points written in one batch by prediction backends
+var WriteBatchSize = 500
+
+// PredictedPoint struct store predicted value read from prediction storage
+// run fields are empty for points written without run, intervals and quantiles are empty for points written without bounds
+// days are set only on period summaries
+type PredictedPoint struct {
+	Measurement        string
+	DayIndex           int
+	Value              float64
+	AverageOrderAmount float64
+	Time               time.Time
+	RunId              string
+	Model              string
+	ModelVersion       string
+	WeightsHash        string
+	Intervals          []Interval
+	Quantiles          []Quantile
+	Days               []Contribution
+}
+
+// FailedPoint struct store point which was not written with its error
+type FailedPoint struct {
+	Point PredictedPoint
+	Err   error
+}
+
+// WriteError function return error summarising failed points, nil when all points were written
+func WriteError(failed []FailedPoint, total int) error {
+	if len(failed) == 0 {
+		return nil
+	}
+	return errors.New(strconv.Itoa(len(failed)) + " of " + strconv.Itoa(total) + " points not written: " + failed[0].Err.Error())
+}
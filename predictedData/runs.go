@@ -0,0 +1,109 @@
+package predictedData
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Run struct store identification of prediction run written as tags of its points
+// run id starts with creation time, so later runs have greater ids
+type Run struct {
+	Id           string
+	Model        string
+	ModelVersion string
+	WeightsHash  string
+}
+
+// RunDiff struct store values of same point in two runs
+type RunDiff struct {
+	Measurement string
+	DayIndex    int
+	Time        time.Time
+	Before      float64
+	After       float64
+	Difference  float64
+}
+
+// NewRun function return new run of model version with hash of weights it uses
+func NewRun(model string, modelVersion string, weightsHash string) Run {
+	id := time.Now().UTC().Format("20060102T150405Z") + "-" + strings.Split(uuid.New().String(), "-")[0]
+	return Run{Id: id, Model: model, ModelVersion: modelVersion, WeightsHash: weightsHash}
+}
+
+// Tag function return points tagged by run
+func (run Run) Tag(points []PredictedPoint) []PredictedPoint {
+	result := make([]PredictedPoint, len(points))
+	for i, p := range points {
+		p.RunId, p.Model, p.ModelVersion, p.WeightsHash = run.Id, run.Model, run.ModelVersion, run.WeightsHash
+		result[i] = p
+	}
+	return result
+}
+
+// Runs function return runs of points ordered from oldest, points written without run are skipped
+func Runs(points []PredictedPoint) []Run {
+	found := map[string]Run{}
+	for _, p := range points {
+		if p.RunId != "" {
+			found[p.RunId] = Run{Id: p.RunId, Model: p.Model, ModelVersion: p.ModelVersion, WeightsHash: p.WeightsHash}
+		}
+	}
+	var result []Run
+	for _, run := range found {
+		result = append(result, run)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Id < result[j].Id })
+	return result
+}
+
+// LatestRun function return latest run of points, empty run when points have no run
+func LatestRun(points []PredictedPoint) Run {
+	runs := Runs(points)
+	if len(runs) == 0 {
+		return Run{}
+	}
+	return runs[len(runs)-1]
+}
+
+// RunPoints function return points of run
+func RunPoints(points []PredictedPoint, runId string) []PredictedPoint {
+	var result []PredictedPoint
+	for _, p := range points {
+		if p.RunId == runId {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// DiffRuns function return differences of points present in both runs, horizon limits day index when positive
+func DiffRuns(points []PredictedPoint, before string, after string, horizon int) []RunDiff {
+	type key struct {
+		measurement string
+		dayIndex    int
+		time        int64
+	}
+	previous := map[key]float64{}
+	for _, p := range RunPoints(points, before) {
+		previous[key{p.Measurement, p.DayIndex, p.Time.UnixNano()}] = p.Value
+	}
+	var result []RunDiff
+	for _, p := range RunPoints(points, after) {
+		if horizon > 0 && p.DayIndex != horizon {
+			continue
+		}
+		if v, ok := previous[key{p.Measurement, p.DayIndex, p.Time.UnixNano()}]; ok {
+			result = append(result, RunDiff{Measurement: p.Measurement, DayIndex: p.DayIndex, Time: p.Time, Before: v, After: p.Value, Difference: p.Value - v})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].Time.Equal(result[j].Time) {
+			return result[i].Time.Before(result[j].Time)
+		}
+		return result[i].DayIndex < result[j].DayIndex
+	})
+	return result
+}
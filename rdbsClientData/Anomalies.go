@@ -0,0 +1,26 @@
+package rdbsClientData
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type Anomalies struct {
+	gorm.Model
+	Id          string    `gorm:"primary_key; unique"`
+	StoreId     string    `gorm:"uniqueIndex:idx_anomalies_point"`
+	Measurement string    `gorm:"uniqueIndex:idx_anomalies_point"`
+	Day         time.Time `gorm:"type:date;uniqueIndex:idx_anomalies_point"`
+	Value       float64
+	Expected    float64
+	Score       float64
+	Method      string
+	Status      string `gorm:"default:flagged"`
+}
+
+func (anomaly *Anomalies) BeforeCreate(db *gorm.DB) error {
+	anomaly.Id = uuid.New().String()
+	return nil
+}
@@ -0,0 +1,26 @@
+package rdbsClientData
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type Attributions struct {
+	gorm.Model
+	Id               string `gorm:"primary_key; unique"`
+	StoreId          string `gorm:"index"`
+	OrderId          string `gorm:"index"`
+	AttributionModel string
+	Tag              string
+	Day              time.Time `gorm:"type:date"`
+	Weight           float64
+	Revenue          float64
+	RevenueNet       float64
+}
+
+func (attribution *Attributions) BeforeCreate(db *gorm.DB) error {
+	attribution.Id = uuid.New().String()
+	return nil
+}
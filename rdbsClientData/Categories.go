@@ -0,0 +1,20 @@
+package rdbsClientData
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type Categories struct {
+	gorm.Model
+	Id       string `gorm:"primary_key; unique"`
+	StoreId  string `gorm:"index"`
+	ParentId string `gorm:"index"`
+	Code     string
+	Name     string
+}
+
+func (category *Categories) BeforeCreate(db *gorm.DB) error {
+	category.Id = uuid.New().String()
+	return nil
+}
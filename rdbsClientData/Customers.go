@@ -0,0 +1,22 @@
+package rdbsClientData
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type Customers struct {
+	gorm.Model
+	Id          string `gorm:"primary_key; unique"`
+	StoreId     string `gorm:"uniqueIndex:idx_customers_store_key"`
+	CustomerKey string `gorm:"uniqueIndex:idx_customers_store_key"`
+	FirstOrder  time.Time
+	LastOrder   time.Time
+}
+
+func (customer *Customers) BeforeCreate(db *gorm.DB) error {
+	customer.Id = uuid.New().String()
+	return nil
+}
@@ -0,0 +1,26 @@
+package rdbsClientData
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type Forecasts struct {
+	gorm.Model
+	Id          string    `gorm:"primary_key; unique"`
+	StoreId     string    `gorm:"uniqueIndex:idx_forecasts_point"`
+	ProductCode string    `gorm:"uniqueIndex:idx_forecasts_point"`
+	Measurement string    `gorm:"uniqueIndex:idx_forecasts_point"`
+	Day         time.Time `gorm:"type:date;uniqueIndex:idx_forecasts_point"`
+	Horizon     int       `gorm:"uniqueIndex:idx_forecasts_point"`
+	Value       float64
+	Actual      float64
+	HasActual   bool
+}
+
+func (forecast *Forecasts) BeforeCreate(db *gorm.DB) error {
+	forecast.Id = uuid.New().String()
+	return nil
+}
@@ -0,0 +1,22 @@
+package rdbsClientData
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type Holidays struct {
+	gorm.Model
+	Id          string    `gorm:"primary_key; unique"`
+	CountryCode string    `gorm:"uniqueIndex:idx_holidays_day"`
+	Day         time.Time `gorm:"type:date;uniqueIndex:idx_holidays_day"`
+	Name        string    `gorm:"uniqueIndex:idx_holidays_day"`
+	Kind        string
+}
+
+func (holiday *Holidays) BeforeCreate(db *gorm.DB) error {
+	holiday.Id = uuid.New().String()
+	return nil
+}
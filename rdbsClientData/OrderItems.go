@@ -12,6 +12,8 @@ type OrderItems struct {
 	Id          string `gorm:"primary_key; unique"`
 	UnitPrice   float64
 	Quantity    int8
+	TaxRate     float64
+	Discount    float64
 	ProductCode string
 	Order       string
 	ProductName string
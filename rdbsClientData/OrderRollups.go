@@ -0,0 +1,27 @@
+package rdbsClientData
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type OrderRollups struct {
+	gorm.Model
+	Id          string    `gorm:"primary_key; unique"`
+	StoreId     string    `gorm:"uniqueIndex:idx_order_rollups_key"`
+	ProductCode string    `gorm:"uniqueIndex:idx_order_rollups_key"`
+	Day         time.Time `gorm:"type:date;uniqueIndex:idx_order_rollups_key"`
+	Tag         string    `gorm:"uniqueIndex:idx_order_rollups_key"`
+	Channel     string    `gorm:"uniqueIndex:idx_order_rollups_key"`
+	Orders      int
+	Quantity    int
+	Amount      float64
+	AmountNet   float64
+}
+
+func (rollup *OrderRollups) BeforeCreate(db *gorm.DB) error {
+	rollup.Id = uuid.New().String()
+	return nil
+}
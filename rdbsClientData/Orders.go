@@ -11,11 +11,19 @@ type Orders struct {
 	gorm.Model
 	Id              string `gorm:"primary_key; unique"`
 	Amount          float64
+	AmountNet       float64
+	Tax             float64
+	Shipping        float64
+	Discount        float64
+	Breakdown       bool
 	Currency        string
 	StoreId         string
 	ExternalOrderId string
 	Tag             string
-	Store           rdbsClientInfo.Stores `gorm:"foreignKey:Order"`
+	CustomerId      string `gorm:"index"`
+	Fingerprint     string
+	Channel         string                `gorm:"default:web;index"`
+	Store           rdbsClientInfo.Stores `gorm:"-"`
 }
 
 func (order *Orders) BeforeCreate(db *gorm.DB) error {
@@ -13,8 +13,10 @@ type Products struct {
 	Quantity    int8
 	ProductCode string
 	Name        string
+	ParentCode  string `gorm:"index"`
+	CategoryId  string `gorm:"index"`
 	StoreId     string
-	Store       rdbsClientInfo.Stores `gorm:"foreignKey:Order"`
+	Store       rdbsClientInfo.Stores `gorm:"-"`
 }
 
 func (products *Products) BeforeCreate(db *gorm.DB) error {
@@ -0,0 +1,23 @@
+package rdbsClientData
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type Promotions struct {
+	gorm.Model
+	Id       string `gorm:"primary_key; unique"`
+	StoreId  string `gorm:"index"`
+	Name     string
+	From     time.Time `gorm:"type:date"`
+	To       time.Time `gorm:"type:date"`
+	Discount float64
+}
+
+func (promotion *Promotions) BeforeCreate(db *gorm.DB) error {
+	promotion.Id = uuid.New().String()
+	return nil
+}
@@ -0,0 +1,22 @@
+package rdbsClientData
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// RollupDeletes rows hard deleted from visitors or orders, filled by trigger and consumed by RefreshRollups
+type RollupDeletes struct {
+	gorm.Model
+	Id           string `gorm:"primary_key; unique"`
+	StoreId      string `gorm:"index"`
+	Source       string
+	RowCreatedAt time.Time
+}
+
+func (deleted *RollupDeletes) BeforeCreate(db *gorm.DB) error {
+	deleted.Id = uuid.New().String()
+	return nil
+}
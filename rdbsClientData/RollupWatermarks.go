@@ -0,0 +1,20 @@
+package rdbsClientData
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type RollupWatermarks struct {
+	gorm.Model
+	Id          string `gorm:"primary_key; unique"`
+	StoreId     string `gorm:"uniqueIndex"`
+	RefreshedAt time.Time
+}
+
+func (watermark *RollupWatermarks) BeforeCreate(db *gorm.DB) error {
+	watermark.Id = uuid.New().String()
+	return nil
+}
@@ -0,0 +1,18 @@
+package rdbsClientData
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type StoreCountries struct {
+	gorm.Model
+	Id          string `gorm:"primary_key; unique"`
+	StoreId     string `gorm:"uniqueIndex"`
+	CountryCode string
+}
+
+func (storeCountry *StoreCountries) BeforeCreate(db *gorm.DB) error {
+	storeCountry.Id = uuid.New().String()
+	return nil
+}
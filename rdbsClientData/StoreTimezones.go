@@ -0,0 +1,18 @@
+package rdbsClientData
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type StoreTimezones struct {
+	gorm.Model
+	Id       string `gorm:"primary_key; unique"`
+	StoreId  string `gorm:"uniqueIndex"`
+	Timezone string
+}
+
+func (storeTimezone *StoreTimezones) BeforeCreate(db *gorm.DB) error {
+	storeTimezone.Id = uuid.New().String()
+	return nil
+}
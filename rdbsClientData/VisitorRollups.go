@@ -0,0 +1,23 @@
+package rdbsClientData
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type VisitorRollups struct {
+	gorm.Model
+	Id          string    `gorm:"primary_key; unique"`
+	StoreId     string    `gorm:"uniqueIndex:idx_visitor_rollups_key"`
+	ProductCode string    `gorm:"uniqueIndex:idx_visitor_rollups_key"`
+	Day         time.Time `gorm:"type:date;uniqueIndex:idx_visitor_rollups_key"`
+	Tag         string    `gorm:"uniqueIndex:idx_visitor_rollups_key"`
+	Visitors    int
+}
+
+func (rollup *VisitorRollups) BeforeCreate(db *gorm.DB) error {
+	rollup.Id = uuid.New().String()
+	return nil
+}
@@ -16,7 +16,8 @@ type Visitors struct {
 	ProductCode string
 	Header      string
 	Tag         string
-	Store       rdbsClientInfo.Stores `gorm:"foreignKey:Order"`
+	Fingerprint string                `gorm:"index"`
+	Store       rdbsClientInfo.Stores `gorm:"-"`
 }
 
 func (visitor *Visitors) BeforeCreate(db *gorm.DB) error {
@@ -12,7 +12,7 @@ type VisitorsOffline struct {
 	Id      string `gorm:"primary_key; unique"`
 	Info    string
 	StoreId string
-	Store   rdbsClientInfo.Stores `gorm:"foreignKey:Order"`
+	Store   rdbsClientInfo.Stores `gorm:"-"`
 }
 
 func (visitorOffline *VisitorsOffline) BeforeCreate(db *gorm.DB) error {
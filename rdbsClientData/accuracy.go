@@ -0,0 +1,245 @@
+package rdbsClientData
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// Measurements of tracked forecasts
+const (
+	MeasurementVisitors = "visitors"
+	MeasurementOrders   = "orders"
+	MeasurementQuantity = "quantity"
+	MeasurementAmount   = "amount"
+)
+
+// DefaultAccuracyWindow number of days used by GetPredictionR2
+const DefaultAccuracyWindow = 30
+
+// ForecastAccuracy struct store error metrics of forecasts compared with actual values
+type ForecastAccuracy struct {
+	ProductCode string
+	Horizon     int
+	Count       int
+	R2          float64
+	MAPE        float64
+	SMAPE       float64
+	RMSE        float64
+	Bias        float64
+}
+
+// forecastPoint struct store forecast value with its actual value
+type forecastPoint struct {
+	ProductCode string
+	Horizon     int
+	Value       float64
+	Actual      float64
+}
+
+// AddForecast function to store predicted value for day, product and horizon in days, empty product code for store
+// repeated prediction for same day and horizon replaces previous value
+func (client *ClientData) AddForecast(storeId string, productCode string, measurement string, day time.Time, horizon int, value float64) Forecasts {
+	forecast := Forecasts{StoreId: storeId, ProductCode: productCode, Measurement: measurement, Day: day, Horizon: horizon, Value: value}
+	client.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "store_id"}, {Name: "product_code"}, {Name: "measurement"}, {Name: "day"}, {Name: "horizon"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value", "updated_at"}),
+	}).Create(&forecast)
+	return forecast
+}
+
+// actualsBatch number of forecast days updated by one statement
+const actualsBatch = 5000
+
+// UpdateForecastActuals function to join stored forecasts between from and to with actual values from series
+// days from today of store timezone on are skipped since they are not complete yet
+func (client *ClientData) UpdateForecastActuals(storeId string, from string, to string) {
+	if yesterday := client.storeYesterday(storeId); to > yesterday {
+		to = yesterday
+	}
+	if to < from {
+		return
+	}
+	var keys []Forecasts
+	client.db.Model(&Forecasts{}).Distinct("product_code", "measurement").
+		Where("store_id = ? AND day >= ? AND day <= ?", storeId, from, to).Find(&keys)
+
+	var rows []string
+	var args []interface{}
+	for _, key := range keys {
+		params := map[string]interface{}{"from": from, "to": to, "store_id": storeId}
+		if key.ProductCode != "" {
+			params["product_code"] = key.ProductCode
+		}
+		for day, actual := range client.measurementSeries(params, key.Measurement) {
+			rows = append(rows, "(?, ?, CAST(? AS date), CAST(? AS double precision))")
+			args = append(args, key.ProductCode, key.Measurement, day.Format("2006-01-02"), actual)
+		}
+	}
+	for start := 0; start < len(rows); start += actualsBatch {
+		end := start + actualsBatch
+		if end > len(rows) {
+			end = len(rows)
+		}
+		client.db.Exec("UPDATE forecasts SET actual = v.actual, has_actual = true, updated_at = now() FROM (VALUES "+strings.Join(rows[start:end], ", ")+
+			") AS v (product_code, measurement, day, actual) WHERE forecasts.store_id = ? AND forecasts.product_code = v.product_code "+
+			"AND forecasts.measurement = v.measurement AND forecasts.day = v.day", append(args[4*start:4*end:4*end], storeId)...)
+	}
+}
+
+// storeYesterday function return previous day in store timezone as date string, last complete day of store
+func (client *ClientData) storeYesterday(storeId string) string {
+	var day string
+	client.db.Raw("SELECT ((now() AT TIME ZONE "+storeZone+")::date - 1)::text", map[string]interface{}{"store_id": storeId}).Scan(&day)
+	return day
+}
+
+// forecastPoints function return forecasts with actual values by params
+// params store_id, from, to and optional measurement (orders by default), product_code and horizon
+func (client *ClientData) forecastPoints(params map[string]interface{}) []forecastPoint {
+	var result []forecastPoint
+	query := "SELECT product_code, horizon, value, actual FROM forecasts WHERE store_id = @store_id AND measurement = @measurement " +
+		"AND has_actual AND deleted_at IS NULL AND day >= CAST(@from AS date) AND day <= CAST(@to AS date)"
+	if _, ok := params["product_code"]; ok {
+		query += " AND product_code = @product_code"
+	}
+	if _, ok := params["horizon"]; ok {
+		query += " AND horizon = @horizon"
+	}
+	values := map[string]interface{}{"measurement": MeasurementOrders}
+	for k, v := range params {
+		values[k] = v
+	}
+	client.db.Raw(query, values).Scan(&result)
+	return result
+}
+
+// GetForecasts function return recorded forecasts ordered by day
+// params store_id, from, to, measurement (orders by default), horizon (1 by default) and product_code (store forecasts by default)
+func (client *ClientData) GetForecasts(params map[string]interface{}) []Forecasts {
+	var result []Forecasts
+	values := map[string]interface{}{"measurement": MeasurementOrders, "horizon": 1, "product_code": ""}
+	for k, v := range params {
+		values[k] = v
+	}
+	client.db.Where("store_id = @store_id AND product_code = @product_code AND measurement = @measurement AND horizon = @horizon "+
+		"AND day >= CAST(@from AS date) AND day <= CAST(@to AS date)", values).Order("day").Find(&result)
+	return result
+}
+
+// GetForecastAccuracy function return error metrics of all forecasts by params, see forecastPoints
+func (client *ClientData) GetForecastAccuracy(params map[string]interface{}) ForecastAccuracy {
+	return accuracy(client.forecastPoints(params))
+}
+
+// GetForecastAccuracyPerProduct function return error metrics for each product by params, see forecastPoints
+func (client *ClientData) GetForecastAccuracyPerProduct(params map[string]interface{}) []ForecastAccuracy {
+	groups := map[string][]forecastPoint{}
+	var order []string
+	for _, p := range client.forecastPoints(params) {
+		if _, ok := groups[p.ProductCode]; !ok {
+			order = append(order, p.ProductCode)
+		}
+		groups[p.ProductCode] = append(groups[p.ProductCode], p)
+	}
+	var result []ForecastAccuracy
+	for _, productCode := range order {
+		a := accuracy(groups[productCode])
+		a.ProductCode = productCode
+		result = append(result, a)
+	}
+	return result
+}
+
+// GetForecastAccuracyPerHorizon function return error metrics for each horizon by params, see forecastPoints
+func (client *ClientData) GetForecastAccuracyPerHorizon(params map[string]interface{}) []ForecastAccuracy {
+	groups := map[int][]forecastPoint{}
+	maxHorizon := 0
+	for _, p := range client.forecastPoints(params) {
+		groups[p.Horizon] = append(groups[p.Horizon], p)
+		if p.Horizon > maxHorizon {
+			maxHorizon = p.Horizon
+		}
+	}
+	var result []ForecastAccuracy
+	for horizon := 0; horizon <= maxHorizon; horizon++ {
+		if points, ok := groups[horizon]; ok {
+			a := accuracy(points)
+			a.Horizon = horizon
+			result = append(result, a)
+		}
+	}
+	return result
+}
+
+// GetPredictionR2Window function return R2 of store orders forecasts over last complete days, today of store timezone is excluded
+func (client *ClientData) GetPredictionR2Window(storeId string, days int) float64 {
+	yesterday := client.storeYesterday(storeId)
+	last, err := time.Parse("2006-01-02", yesterday)
+	if err != nil {
+		return 0
+	}
+	return client.GetForecastAccuracy(map[string]interface{}{
+		"store_id":     storeId,
+		"product_code": "",
+		"from":         last.AddDate(0, 0, 1-days).Format("2006-01-02"),
+		"to":           yesterday,
+	}).R2
+}
+
+// Accuracy function return error metrics of forecast values compared with actual values of same length
+func Accuracy(values []float64, actuals []float64) ForecastAccuracy {
+	points := make([]forecastPoint, 0, len(values))
+	for i := range values {
+		if i < len(actuals) {
+			points = append(points, forecastPoint{Value: values[i], Actual: actuals[i]})
+		}
+	}
+	return accuracy(points)
+}
+
+// accuracy function compute R2, MAPE, sMAPE, RMSE and bias of forecast points
+// MAPE skips zero actual values, percentage errors are fractions
+func accuracy(points []forecastPoint) ForecastAccuracy {
+	result := ForecastAccuracy{Count: len(points)}
+	if len(points) == 0 {
+		return result
+	}
+	mean := 0.0
+	for _, p := range points {
+		mean += p.Actual
+	}
+	mean /= float64(len(points))
+
+	var squared, total, bias, ape, sape float64
+	apeCount, sapeCount := 0, 0
+	for _, p := range points {
+		e := p.Value - p.Actual
+		squared += e * e
+		total += (p.Actual - mean) * (p.Actual - mean)
+		bias += e
+		if p.Actual != 0 {
+			ape += math.Abs(e / p.Actual)
+			apeCount++
+		}
+		if d := math.Abs(p.Actual) + math.Abs(p.Value); d != 0 {
+			sape += 2 * math.Abs(e) / d
+			sapeCount++
+		}
+	}
+	n := float64(len(points))
+	result.RMSE = math.Sqrt(squared / n)
+	result.Bias = bias / n
+	if total != 0 {
+		result.R2 = 1 - squared/total
+	}
+	if apeCount > 0 {
+		result.MAPE = ape / float64(apeCount)
+	}
+	if sapeCount > 0 {
+		result.SMAPE = sape / float64(sapeCount)
+	}
+	return result
+}
@@ -0,0 +1,323 @@
+package rdbsClientData
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// Anomaly detection methods, robust z-score of whole series or of residuals from same weekday median
+const (
+	AnomalyRobust   = "robust"
+	AnomalySeasonal = "seasonal"
+)
+
+// Anomaly statuses, detected points are flagged, manual override confirms or dismisses them
+const (
+	AnomalyFlagged   = "flagged"
+	AnomalyConfirmed = "confirmed"
+	AnomalyDismissed = "dismissed"
+)
+
+// Series anomaly handling passed as "anomalies" param, mask marks anomalous days, impute also replaces value by expected one
+const (
+	AnomaliesMask   = "mask"
+	AnomaliesImpute = "impute"
+)
+
+// DefaultAnomalyThreshold absolute robust z-score above which point is flagged
+const DefaultAnomalyThreshold = 3.5
+
+// anomalyMinPoints minimal series length for detection
+const anomalyMinPoints = 14
+
+// ValueByDay struct store value of measurement for series bucket
+type ValueByDay struct {
+	Value float64
+	Day   time.Time
+}
+
+// GetMeasurementSeries function return ordered series of measurement param (visitors, orders, quantity or amount)
+// other params are passed to series function of measurement
+func (client *ClientData) GetMeasurementSeries(params map[string]interface{}) []ValueByDay {
+	var result []ValueByDay
+	switch params["measurement"] {
+	case MeasurementVisitors:
+		for _, v := range client.GetVisitorsSeries(params) {
+			result = append(result, ValueByDay{float64(v.Visitors), v.Day})
+		}
+	case MeasurementOrders, MeasurementQuantity:
+		for _, v := range client.GetOrdersSeries(params) {
+			if params["measurement"] == MeasurementOrders {
+				result = append(result, ValueByDay{float64(v.Orders), v.Day})
+			} else {
+				result = append(result, ValueByDay{float64(v.Quantity), v.Day})
+			}
+		}
+	case MeasurementAmount:
+		for _, v := range client.GetAmountSeries(params) {
+			result = append(result, ValueByDay{v.Value, v.Day})
+		}
+	}
+	return result
+}
+
+// measurementSeries function return daily values of measurement by series params
+func (client *ClientData) measurementSeries(params map[string]interface{}, measurement string) map[time.Time]float64 {
+	values := map[string]interface{}{"measurement": measurement}
+	for k, v := range params {
+		if k != "measurement" {
+			values[k] = v
+		}
+	}
+	result := map[time.Time]float64{}
+	for _, v := range client.GetMeasurementSeries(values) {
+		result[v.Day] = v.Value
+	}
+	return result
+}
+
+// DetectAnomalies function to flag anomalous days of store daily series between from and to
+// params store_id, from, to, measurement and optional method (seasonal by default) and threshold
+// previous flags in range are replaced, confirmed and dismissed days keep their status
+func (client *ClientData) DetectAnomalies(params map[string]interface{}) []Anomalies {
+	measurement, _ := params["measurement"].(string)
+	storeId, ok := params["store_id"].(string)
+	if !ok {
+		return nil
+	}
+	method := AnomalySeasonal
+	if params["method"] == AnomalyRobust {
+		method = AnomalyRobust
+	}
+	threshold := DefaultAnomalyThreshold
+	if t, ok := params["threshold"].(float64); ok && t > 0 {
+		threshold = t
+	}
+
+	values := client.measurementSeries(map[string]interface{}{"from": params["from"], "to": params["to"], "store_id": params["store_id"]}, measurement)
+	var days []time.Time
+	for day := range values {
+		days = append(days, day)
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+
+	client.db.Unscoped().Where("store_id = ? AND measurement = ? AND day >= ? AND day <= ? AND status = ?",
+		params["store_id"], measurement, params["from"], params["to"], AnomalyFlagged).Delete(&Anomalies{})
+
+	var result []Anomalies
+	if len(days) < anomalyMinPoints {
+		return result
+	}
+	series := make([]float64, len(days))
+	for i, day := range days {
+		series[i] = values[day]
+	}
+	expected, scores := anomalyScores(series, days, method)
+	for i, day := range days {
+		if math.Abs(scores[i]) <= threshold {
+			continue
+		}
+		anomaly := Anomalies{StoreId: storeId, Measurement: measurement, Day: day, Value: series[i],
+			Expected: expected[i], Score: scores[i], Method: method, Status: AnomalyFlagged}
+		client.db.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "store_id"}, {Name: "measurement"}, {Name: "day"}},
+			DoUpdates: clause.AssignmentColumns([]string{"value", "expected", "score", "method", "updated_at"}),
+		}).Create(&anomaly)
+		result = append(result, anomaly)
+	}
+	return result
+}
+
+// anomalyScores function return expected values and robust z-scores of series
+// seasonal method expects median of same weekday and scores residuals from it
+func anomalyScores(series []float64, days []time.Time, method string) ([]float64, []float64) {
+	expected := make([]float64, len(series))
+	if method == AnomalySeasonal {
+		weekdays := map[time.Weekday][]float64{}
+		for i, day := range days {
+			weekdays[day.Weekday()] = append(weekdays[day.Weekday()], series[i])
+		}
+		for i, day := range days {
+			expected[i] = median(weekdays[day.Weekday()])
+		}
+	} else {
+		m := median(series)
+		for i := range expected {
+			expected[i] = m
+		}
+	}
+	residuals := make([]float64, len(series))
+	for i := range series {
+		residuals[i] = series[i] - expected[i]
+	}
+	center := median(residuals)
+	deviations := make([]float64, len(series))
+	meanDeviation := 0.0
+	for i := range residuals {
+		deviations[i] = math.Abs(residuals[i] - center)
+		meanDeviation += deviations[i]
+	}
+	meanDeviation /= float64(len(series))
+	// consistency constants make both scales comparable with standard deviation of normal distribution
+	scale := median(deviations) / 0.6745
+	if scale == 0 {
+		scale = meanDeviation * 1.2533
+	}
+	scores := make([]float64, len(series))
+	if scale == 0 {
+		return expected, scores
+	}
+	for i := range residuals {
+		scores[i] = (residuals[i] - center) / scale
+	}
+	return expected, scores
+}
+
+// median function return median of values
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+// SetAnomalyStatus function to confirm or dismiss anomaly of store day, day not detected before is added with confirmed status
+// expected value of added day is median of same weekday in previous eight weeks
+// status is one of AnomalyFlagged, AnomalyConfirmed or AnomalyDismissed
+func (client *ClientData) SetAnomalyStatus(storeId string, measurement string, day time.Time, status string) (Anomalies, error) {
+	var anomaly Anomalies
+	if status != AnomalyFlagged && status != AnomalyConfirmed && status != AnomalyDismissed {
+		return anomaly, fmt.Errorf("unknown anomaly status %s", status)
+	}
+	client.db.Model(&Anomalies{}).Where("store_id = ? AND measurement = ? AND day = ?", storeId, measurement, day.Format("2006-01-02")).Find(&anomaly)
+	if anomaly.Id != "" {
+		client.db.Model(&Anomalies{}).Where("id = ?", anomaly.Id).Update("status", status)
+		anomaly.Status = status
+		return anomaly, nil
+	}
+
+	values := client.measurementSeries(map[string]interface{}{
+		"from":     day.AddDate(0, 0, -56).Format("2006-01-02"),
+		"to":       day.Format("2006-01-02"),
+		"store_id": storeId,
+	}, measurement)
+	var history []float64
+	for d, v := range values {
+		if d.Weekday() == day.Weekday() && d.Before(day) {
+			history = append(history, v)
+		}
+	}
+	anomaly = Anomalies{StoreId: storeId, Measurement: measurement, Day: day, Expected: median(history), Method: "manual", Status: status}
+	for d, v := range values {
+		if d.Format("2006-01-02") == day.Format("2006-01-02") {
+			anomaly.Value = v
+		}
+	}
+	return anomaly, client.db.Create(&anomaly).Error
+}
+
+// GetAnomalies function return anomalies of store between from and to
+// params store_id, from, to and optional measurement and status
+func (client *ClientData) GetAnomalies(params map[string]interface{}) []Anomalies {
+	var result []Anomalies
+	query := client.db.Model(&Anomalies{}).Where("store_id = ? AND day >= ? AND day <= ?", params["store_id"], params["from"], params["to"])
+	if measurement, ok := params["measurement"]; ok {
+		query = query.Where("measurement = ?", measurement)
+	}
+	if status, ok := params["status"]; ok {
+		query = query.Where("status = ?", status)
+	}
+	query.Order("day").Find(&result)
+	return result
+}
+
+// activeAnomalies function return not dismissed anomalies of measurement by day when params ask for mask or impute
+// anomalies are daily, so other granularities are returned unchanged
+func (client *ClientData) activeAnomalies(params map[string]interface{}, measurement string) map[string]Anomalies {
+	mode := params["anomalies"]
+	if (mode != AnomaliesMask && mode != AnomaliesImpute) || granularity(params) != GranularityDay {
+		return nil
+	}
+	var anomalies []Anomalies
+	client.db.Model(&Anomalies{}).Where("store_id = ? AND measurement = ? AND day >= ? AND day <= ? AND status <> ?",
+		params["store_id"], measurement, params["from"], params["to"], AnomalyDismissed).Find(&anomalies)
+	result := map[string]Anomalies{}
+	for _, a := range anomalies {
+		result[a.Day.Format("2006-01-02")] = a
+	}
+	return result
+}
+
+// imputeParams series params which keep series comparable with expected values of detected anomalies
+var imputeParams = map[string]bool{"store_id": true, "from": true, "to": true, "granularity": true, "measurement": true, "anomalies": true}
+
+// imputeAnomalies function return if anomalous values should be replaced, expected values are known only for unsplit store series
+// any other filter (product, channel, tag, split or net revenue) changes series, so its values are only masked
+func imputeAnomalies(params map[string]interface{}) bool {
+	if params["anomalies"] != AnomaliesImpute {
+		return false
+	}
+	for k, v := range params {
+		if !imputeParams[k] && !(k == "revenue" && v == RevenueGross) {
+			return false
+		}
+	}
+	return true
+}
+
+// applyVisitorsAnomalies function to mask or impute anomalous days of visitors series by params
+func (client *ClientData) applyVisitorsAnomalies(params map[string]interface{}, series []VisitorsByDay) {
+	anomalies := client.activeAnomalies(params, MeasurementVisitors)
+	for i := range series {
+		if a, ok := anomalies[series[i].Day.Format("2006-01-02")]; ok {
+			series[i].Anomaly = true
+			if imputeAnomalies(params) {
+				series[i].Visitors = int(math.Round(a.Expected))
+			}
+		}
+	}
+}
+
+// applyOrdersAnomalies function to mask or impute anomalous days of orders series by params
+func (client *ClientData) applyOrdersAnomalies(params map[string]interface{}, series []OrdersByDay) {
+	orders := client.activeAnomalies(params, MeasurementOrders)
+	quantity := client.activeAnomalies(params, MeasurementQuantity)
+	for i := range series {
+		day := series[i].Day.Format("2006-01-02")
+		if a, ok := orders[day]; ok {
+			series[i].Anomaly = true
+			if imputeAnomalies(params) {
+				series[i].Orders = int(math.Round(a.Expected))
+			}
+		}
+		if a, ok := quantity[day]; ok {
+			series[i].Anomaly = true
+			if imputeAnomalies(params) {
+				series[i].Quantity = int(math.Round(a.Expected))
+			}
+		}
+	}
+}
+
+// applyAmountAnomalies function to mask or impute anomalous days of amount series by params
+func (client *ClientData) applyAmountAnomalies(params map[string]interface{}, series []AmountByDay) {
+	anomalies := client.activeAnomalies(params, MeasurementAmount)
+	for i := range series {
+		if a, ok := anomalies[series[i].Day.Format("2006-01-02")]; ok {
+			series[i].Anomaly = true
+			if imputeAnomalies(params) {
+				series[i].Value = a.Expected
+			}
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package rdbsClientData
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// Attribution models to split order credit between preceding visitor hits
+const (
+	AttributionFirstTouch = "first_touch"
+	AttributionLastTouch  = "last_touch"
+	AttributionLinear     = "linear"
+)
+
+// AttributionModels list of supported attribution models
+var AttributionModels = []string{AttributionFirstTouch, AttributionLastTouch, AttributionLinear}
+
+// AttributionByDay struct store attributed conversions and revenue for tag and day
+type AttributionByDay struct {
+	Day            time.Time
+	Tag            string
+	Visitors       int
+	Conversions    float64
+	Revenue        float64
+	ConversionRate float64
+}
+
+// Fingerprint function return visitor fingerprint from ip and header
+func Fingerprint(ip string, header string) string {
+	hash := sha256.Sum256([]byte(ip + "|" + header))
+	return hex.EncodeToString(hash[:])
+}
+
+// Attribute function to link orders in date range to preceding visitor hits and store credit per tag
+// hits are matched by order fingerprint when present, otherwise by order tag, within lookback days before order
+func (client *ClientData) Attribute(from string, to string, store string, model string, lookbackDays int) []Attributions {
+	params := map[string]interface{}{"from": from, "to": to, "store_id": store, "model": model, "lookback": lookbackDays}
+	var result []Attributions
+	client.db.Raw("SELECT order_id, day, tag, sum(weight) AS weight, sum(weight) * max(revenue) AS revenue, sum(weight) * max(revenue_net) AS revenue_net FROM ("+
+		"SELECT order_id, day, revenue, revenue_net, tag, CASE WHEN @model = 'linear' THEN 1.0 / touches WHEN @model = 'first_touch' AND first_rank = 1 THEN 1.0 "+
+		"WHEN @model = 'last_touch' AND last_rank = 1 THEN 1.0 ELSE 0 END AS weight FROM ("+
+		"SELECT o.id AS order_id, "+bucket("o.created_at", GranularityDay)+"::date AS day, o.amount AS revenue, CASE WHEN o.breakdown THEN o.amount_net ELSE o.amount END AS revenue_net, v.tag, "+
+		"row_number() OVER (PARTITION BY o.id ORDER BY v.created_at) AS first_rank, row_number() OVER (PARTITION BY o.id ORDER BY v.created_at DESC) AS last_rank, "+
+		"count(*) OVER (PARTITION BY o.id) AS touches FROM orders o JOIN visitors v ON v.store_id = o.store_id AND v.created_at <= o.created_at "+
+		"AND v.created_at > o.created_at - interval '1 day' * @lookback AND v.header NOT LIKE '%Googlebot%' "+
+		"AND (CASE WHEN coalesce(o.fingerprint, '') <> '' THEN v.fingerprint = o.fingerprint ELSE o.tag <> '' AND v.tag = o.tag END) "+
+		"WHERE o.store_id = @store_id AND "+seriesCondition("o.created_at", GranularityDay)+") t) w "+
+		"WHERE weight > 0 GROUP BY order_id, day, tag", params).Scan(&result)
+
+	client.db.Exec("DELETE FROM attributions WHERE store_id = @store_id AND attribution_model = @model AND day >= CAST(@from AS date) AND day < CAST(@to AS date) + 1", params)
+	for i := range result {
+		result[i].StoreId = store
+		result[i].AttributionModel = model
+	}
+	if len(result) > 0 {
+		client.db.CreateInBatches(&result, 500)
+	}
+	return result
+}
+
+// GetAttributionByDay function return stored attributed conversions and revenue per tag and day
+// params from, to, store_id, model and optional revenue basis, days and tags with visitors and no conversion are included
+func (client *ClientData) GetAttributionByDay(params map[string]interface{}) []AttributionByDay {
+	var result []AttributionByDay
+	revenue := "revenue"
+	if params["revenue"] == RevenueNet {
+		revenue = "revenue_net"
+	}
+	client.db.Raw("SELECT day, tag, coalesce(v.visitors, 0)::int AS visitors, coalesce(a.conversions, 0) AS conversions, coalesce(a.revenue, 0) AS revenue FROM "+
+		"(SELECT day::date AS day, tag, sum(weight) AS conversions, sum("+revenue+") AS revenue FROM attributions "+
+		"WHERE store_id = @store_id AND attribution_model = @model AND deleted_at IS NULL "+
+		"AND day >= CAST(@from AS date) AND day < CAST(@to AS date) + 1 GROUP BY 1, 2) a "+
+		"FULL JOIN (SELECT day::date AS day, tag, sum(visitors) AS visitors FROM visitor_rollups WHERE store_id = @store_id "+
+		"AND day >= CAST(@from AS date) AND day < CAST(@to AS date) + 1 GROUP BY 1, 2) v USING (day, tag) ORDER BY day, tag", params).Scan(&result)
+	for i := range result {
+		if result[i].Visitors > 0 {
+			result[i].ConversionRate = result[i].Conversions / float64(result[i].Visitors)
+		}
+	}
+	return result
+}
+
+// GetAttributionByTag function return stored attributed conversions and revenue per tag summed over date range
+func (client *ClientData) GetAttributionByTag(params map[string]interface{}) []AttributionByDay {
+	days := client.GetAttributionByDay(params)
+	index := map[string]int{}
+	var result []AttributionByDay
+	for _, d := range days {
+		i, ok := index[d.Tag]
+		if !ok {
+			i = len(result)
+			index[d.Tag] = i
+			result = append(result, AttributionByDay{Tag: d.Tag})
+		}
+		result[i].Visitors += d.Visitors
+		result[i].Conversions += d.Conversions
+		result[i].Revenue += d.Revenue
+	}
+	for i := range result {
+		if result[i].Visitors > 0 {
+			result[i].ConversionRate = result[i].Conversions / float64(result[i].Visitors)
+		}
+	}
+	return result
+}
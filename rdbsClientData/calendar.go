@@ -0,0 +1,309 @@
+package rdbsClientData
+
+import (
+	"embed"
+	"encoding/csv"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// holidayFiles bundled holiday rules, one CSV file per country code
+//
+//go:embed holidays/*.csv
+var holidayFiles embed.FS
+
+// Holiday kinds, public holidays and shopping events like Black Friday
+const (
+	HolidayPublic   = "public"
+	HolidayShopping = "shopping"
+)
+
+// CalendarDay struct store calendar features of series bucket
+// weekday is ISO day of week of bucket start, 1 for Monday and 7 for Sunday
+type CalendarDay struct {
+	Day       time.Time
+	Weekday   int
+	Weekend   bool
+	Holiday   bool
+	Holidays  []string
+	Promotion bool
+	Discount  float64
+}
+
+// HolidayCountries function return country codes with bundled holiday rules
+func HolidayCountries() []string {
+	var result []string
+	entries, _ := holidayFiles.ReadDir("holidays")
+	for _, entry := range entries {
+		result = append(result, strings.TrimSuffix(entry.Name(), ".csv"))
+	}
+	return result
+}
+
+// LoadHolidays function to expand bundled holiday rules of country for years and store them, return count of days
+func (client *ClientData) LoadHolidays(countryCode string, fromYear int, toYear int) (int, error) {
+	countryCode = strings.ToUpper(countryCode)
+	file, err := holidayFiles.Open("holidays/" + countryCode + ".csv")
+	if err != nil {
+		return 0, errors.New("no holidays for country " + countryCode)
+	}
+	defer file.Close()
+	rows, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for year := fromYear; year <= toYear; year++ {
+		for i, row := range rows {
+			if i == 0 || len(row) < 2 {
+				continue
+			}
+			day, err := holidayDay(row[0], year)
+			if err != nil {
+				return count, errors.New(countryCode + " rule " + row[0] + ": " + err.Error())
+			}
+			kind := HolidayPublic
+			if len(row) > 2 && row[2] != "" {
+				kind = row[2]
+			}
+			holiday := Holidays{CountryCode: countryCode, Day: day, Name: row[1], Kind: kind}
+			client.db.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "country_code"}, {Name: "day"}, {Name: "name"}},
+				DoUpdates: clause.AssignmentColumns([]string{"kind", "updated_at"}),
+			}).Create(&holiday)
+			count++
+		}
+	}
+	return count, nil
+}
+
+// holidayDay function return day of holiday rule in year
+// rules are fixed MM-DD, easter+N or easter-N, and MM-www-N or MM-www-last for nth weekday of month, all with optional +N days
+func holidayDay(rule string, year int) (time.Time, error) {
+	offset := 0
+	if strings.HasPrefix(rule, "easter") {
+		if rest := strings.TrimPrefix(rule, "easter"); rest != "" {
+			n, err := strconv.Atoi(rest)
+			if err != nil {
+				return time.Time{}, err
+			}
+			offset = n
+		}
+		return easter(year).AddDate(0, 0, offset), nil
+	}
+	if i := strings.Index(rule, "+"); i > 0 {
+		n, err := strconv.Atoi(rule[i+1:])
+		if err != nil {
+			return time.Time{}, err
+		}
+		offset = n
+		rule = rule[:i]
+	}
+	parts := strings.Split(rule, "-")
+	month, err := strconv.Atoi(parts[0])
+	if err != nil || month < 1 || month > 12 {
+		return time.Time{}, errors.New("invalid month")
+	}
+	switch len(parts) {
+	case 2:
+		day, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC).AddDate(0, 0, offset), nil
+	case 3:
+		weekday, ok := map[string]time.Weekday{"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+			"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday}[parts[1]]
+		if !ok {
+			return time.Time{}, errors.New("invalid weekday")
+		}
+		if parts[2] == "last" {
+			day := time.Date(year, time.Month(month)+1, 0, 0, 0, 0, 0, time.UTC)
+			for day.Weekday() != weekday {
+				day = day.AddDate(0, 0, -1)
+			}
+			return day.AddDate(0, 0, offset), nil
+		}
+		n, err := strconv.Atoi(parts[2])
+		if err != nil || n < 1 || n > 5 {
+			return time.Time{}, errors.New("invalid week")
+		}
+		day := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+		for day.Weekday() != weekday {
+			day = day.AddDate(0, 0, 1)
+		}
+		return day.AddDate(0, 0, 7*(n-1)+offset), nil
+	}
+	return time.Time{}, errors.New("invalid rule")
+}
+
+// easter function return Easter Sunday of year in Gregorian calendar
+func easter(year int) time.Time {
+	a := year % 19
+	b := year / 100
+	c := year % 100
+	d := (19*a + b - b/4 - (b-(b+8)/25+1)/3 + 15) % 30
+	e := (32 + 2*(b%4) + 2*(c/4) - d - c%4) % 7
+	f := d + e - 7*((a+11*d+22*e)/451) + 114
+	return time.Date(year, time.Month(f/31), f%31+1, 0, 0, 0, 0, time.UTC)
+}
+
+// GetHolidays function return stored holidays of country between from and to days
+func (client *ClientData) GetHolidays(countryCode string, from string, to string) []Holidays {
+	var result []Holidays
+	client.db.Model(&Holidays{}).Where("country_code = ? AND day >= ? AND day <= ?", strings.ToUpper(countryCode), from, to).Order("day").Find(&result)
+	return result
+}
+
+// SetStoreCountry function to set country of store used for its holidays
+func (client *ClientData) SetStoreCountry(storeId string, countryCode string) {
+	storeCountry := StoreCountries{StoreId: storeId, CountryCode: strings.ToUpper(countryCode)}
+	client.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "store_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"country_code", "updated_at"}),
+	}).Create(&storeCountry)
+}
+
+// CreatePromotion function to store promotion period of store with discount in percent, from and to are inclusive days
+func (client *ClientData) CreatePromotion(storeId string, name string, from time.Time, to time.Time, discount float64) Promotions {
+	promotion := Promotions{StoreId: storeId, Name: name, From: from, To: to, Discount: discount}
+	client.db.Create(&promotion)
+	return promotion
+}
+
+// UpdatePromotion function to update promotion period
+func (client *ClientData) UpdatePromotion(id string, name string, from time.Time, to time.Time, discount float64) Promotions {
+	var promotion Promotions
+	client.db.Model(&Promotions{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"name": name, "from": from, "to": to, "discount": discount})
+	client.db.Model(&Promotions{}).Where("id = ?", id).Find(&promotion)
+	return promotion
+}
+
+// GetPromotions function return promotions of store ordered by start
+func (client *ClientData) GetPromotions(storeId string) []Promotions {
+	var result []Promotions
+	client.db.Model(&Promotions{}).Where("store_id = ?", storeId).Order("\"from\"").Find(&result)
+	return result
+}
+
+// DeletePromotion function to delete promotion
+func (client *ClientData) DeletePromotion(id string) {
+	client.db.Exec("DELETE FROM promotions WHERE id = @id", map[string]interface{}{"id": id})
+}
+
+// GetCalendar function return calendar features of each day between from and to for store
+// params store_id, from and to, holidays are those of store country loaded by LoadHolidays
+func (client *ClientData) GetCalendar(params map[string]interface{}) []CalendarDay {
+	fromDay, _ := params["from"].(string)
+	toDay, _ := params["to"].(string)
+	from, errFrom := time.Parse("2006-01-02", fromDay)
+	to, errTo := time.Parse("2006-01-02", toDay)
+	if errFrom != nil || errTo != nil {
+		return nil
+	}
+
+	var holidays []Holidays
+	client.db.Raw("SELECT holidays.* FROM holidays JOIN store_countries ON store_countries.country_code = holidays.country_code "+
+		"WHERE store_countries.store_id = @store_id AND holidays.deleted_at IS NULL AND holidays.day >= CAST(@from AS date) AND holidays.day <= CAST(@to AS date)", params).Scan(&holidays)
+	var promotions []Promotions
+	client.db.Model(&Promotions{}).Where("store_id = ? AND \"from\" <= ? AND \"to\" >= ?", params["store_id"], params["to"], params["from"]).Find(&promotions)
+
+	names := map[string][]string{}
+	for _, h := range holidays {
+		day := h.Day.Format("2006-01-02")
+		names[day] = append(names[day], h.Name)
+	}
+	var result []CalendarDay
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		weekday := int(day.Weekday())
+		if weekday == 0 {
+			weekday = 7
+		}
+		c := CalendarDay{Day: day, Weekday: weekday, Weekend: weekday >= 6, Holidays: names[day.Format("2006-01-02")]}
+		c.Holiday = len(c.Holidays) > 0
+		for _, p := range promotions {
+			if !day.Before(p.From.UTC().Truncate(24*time.Hour)) && !day.After(p.To.UTC().Truncate(24*time.Hour)) {
+				c.Promotion = true
+				if p.Discount > c.Discount {
+					c.Discount = p.Discount
+				}
+			}
+		}
+		result = append(result, c)
+	}
+	return result
+}
+
+// seriesCalendar function return calendar of series buckets by params
+// bucket longer than day is holiday or promotion when any of its days is, discount is the highest one
+func (client *ClientData) seriesCalendar(params map[string]interface{}) func(time.Time) CalendarDay {
+	fromDay, _ := params["from"].(string)
+	toDay, _ := params["to"].(string)
+	from, errFrom := time.Parse("2006-01-02", fromDay)
+	to, errTo := time.Parse("2006-01-02", toDay)
+	if errFrom != nil || errTo != nil {
+		return func(day time.Time) CalendarDay { return CalendarDay{Day: day} }
+	}
+	// buckets are aligned to week or month start and cover whole last bucket
+	days := client.GetCalendar(map[string]interface{}{
+		"store_id": params["store_id"],
+		"from":     from.AddDate(0, 0, -31).Format("2006-01-02"),
+		"to":       to.AddDate(0, 0, 31).Format("2006-01-02"),
+	})
+	byDay := map[string]CalendarDay{}
+	for _, c := range days {
+		byDay[c.Day.Format("2006-01-02")] = c
+	}
+	g := granularity(params)
+	return func(start time.Time) CalendarDay {
+		end := start.AddDate(0, 0, 1)
+		switch g {
+		case GranularityWeek:
+			end = start.AddDate(0, 0, 7)
+		case GranularityMonth:
+			end = start.AddDate(0, 1, 0)
+		}
+		result := byDay[start.Format("2006-01-02")]
+		result.Day = start
+		for day := start.AddDate(0, 0, 1); day.Before(end); day = day.AddDate(0, 0, 1) {
+			c := byDay[day.Format("2006-01-02")]
+			result.Holiday = result.Holiday || c.Holiday
+			result.Holidays = append(result.Holidays, c.Holidays...)
+			result.Promotion = result.Promotion || c.Promotion
+			if c.Discount > result.Discount {
+				result.Discount = c.Discount
+			}
+		}
+		return result
+	}
+}
+
+// annotateVisitors function to set calendar features of visitors series
+func (client *ClientData) annotateVisitors(params map[string]interface{}, series []VisitorsByDay) {
+	calendar := client.seriesCalendar(params)
+	for i := range series {
+		series[i].Calendar = calendar(series[i].Day)
+	}
+}
+
+// annotateOrders function to set calendar features of orders series
+func (client *ClientData) annotateOrders(params map[string]interface{}, series []OrdersByDay) {
+	calendar := client.seriesCalendar(params)
+	for i := range series {
+		series[i].Calendar = calendar(series[i].Day)
+	}
+}
+
+// annotateAmount function to set calendar features of amount series
+func (client *ClientData) annotateAmount(params map[string]interface{}, series []AmountByDay) {
+	calendar := client.seriesCalendar(params)
+	for i := range series {
+		series[i].Calendar = calendar(series[i].Day)
+	}
+}
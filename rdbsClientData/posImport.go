@@ -0,0 +1,156 @@
+package rdbsClientData
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// posDateLayouts accepted date formats of POS receipt exports
+var posDateLayouts = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"}
+
+// posRequiredColumns columns required in POS receipt export header
+var posRequiredColumns = []string{"receipt_id", "date", "product_code", "quantity", "unit_price"}
+
+// ImportPosReceipts function to import POS receipt export as orders with channel pos
+// csv has header with receipt_id, date, product_code, quantity, unit_price and optional product_name, tax_rate, discount
+// one row per receipt line separated by comma or semicolon, receipts already imported for store are skipped
+// receipts are imported in one transaction, so nothing is imported when any of them fails,
+// receipt times without offset are local times of store timezone
+// returns number of imported receipts
+func (client *ClientData) ImportPosReceipts(reader io.Reader, storeId string, currency string) (int, error) {
+	buffered := bufio.NewReader(reader)
+	first, _ := buffered.Peek(buffered.Size())
+	if i := strings.IndexByte(string(first), '\n'); i >= 0 {
+		first = first[:i]
+	}
+	r := csv.NewReader(buffered)
+	if strings.Count(string(first), ";") > strings.Count(string(first), ",") {
+		r.Comma = ';'
+	}
+	r.TrimLeadingSpace = true
+	header, err := r.Read()
+	if err != nil {
+		return 0, err
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range posRequiredColumns {
+		if _, ok := columns[name]; !ok {
+			return 0, fmt.Errorf("missing column %s in POS export", name)
+		}
+	}
+
+	location, err := client.StoreLocation(storeId)
+	if err != nil {
+		return 0, err
+	}
+
+	var receipts []string
+	details := map[string]*OrderDetail{}
+	items := map[string][]ItemDetail{}
+	line := 1
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			return 0, err
+		}
+		value := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		item := ItemDetail{ProductCode: value("product_code"), ProductName: value("product_name")}
+		quantity, err := strconv.Atoi(value("quantity"))
+		if err != nil {
+			return 0, fmt.Errorf("invalid quantity on line %d: %w", line, err)
+		}
+		if quantity < math.MinInt8 || quantity > math.MaxInt8 {
+			return 0, fmt.Errorf("quantity %d out of range on line %d", quantity, line)
+		}
+		item.Quantity = int8(quantity)
+		if item.UnitPrice, err = parsePosNumber(value("unit_price")); err != nil {
+			return 0, fmt.Errorf("invalid unit_price on line %d: %w", line, err)
+		}
+		if item.TaxRate, err = parsePosNumber(value("tax_rate")); err != nil {
+			return 0, fmt.Errorf("invalid tax_rate on line %d: %w", line, err)
+		}
+		if item.Discount, err = parsePosNumber(value("discount")); err != nil {
+			return 0, fmt.Errorf("invalid discount on line %d: %w", line, err)
+		}
+
+		receipt := value("receipt_id")
+		if receipt == "" {
+			return 0, fmt.Errorf("missing receipt_id on line %d", line)
+		}
+		detail, ok := details[receipt]
+		if !ok {
+			created, err := parsePosDate(value("date"), location)
+			if err != nil {
+				return 0, fmt.Errorf("invalid date on line %d: %w", line, err)
+			}
+			detail = &OrderDetail{ExternalOrderId: receipt, StoreId: storeId, Currency: currency, Channel: ChannelPos, Created: created}
+			details[receipt] = detail
+			receipts = append(receipts, receipt)
+		}
+		gross := item.UnitPrice*float64(item.Quantity) - item.Discount
+		detail.Gross += gross
+		detail.Tax += gross - gross/(1+item.TaxRate/100)
+		detail.Discount += item.Discount
+		items[receipt] = append(items[receipt], item)
+	}
+
+	imported := 0
+	err = client.db.Transaction(func(tx *gorm.DB) error {
+		txClient := ClientData{tx}
+		for _, receipt := range receipts {
+			var count int64
+			tx.Model(&Orders{}).Where("store_id = ? AND external_order_id = ? AND channel = ?", storeId, receipt, ChannelPos).Count(&count)
+			if count > 0 {
+				continue
+			}
+			if err := txClient.AddOrderDetail(*details[receipt], items[receipt]).Error; err != nil {
+				return err
+			}
+			imported++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return imported, nil
+}
+
+// parsePosNumber function parse decimal number with dot or comma separator, empty value is zero
+func parsePosNumber(value string) (float64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.ParseFloat(strings.Replace(value, ",", ".", 1), 64)
+}
+
+// parsePosDate function parse receipt date in one of accepted layouts, date without offset is local time of location
+func parsePosDate(value string, location *time.Location) (time.Time, error) {
+	for _, layout := range posDateLayouts {
+		if t, err := time.ParseInLocation(layout, value, location); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unknown date format %q", value)
+}
@@ -2,8 +2,15 @@
 package rdbsClientData
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 
+	"github.com/google/uuid"
+
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
@@ -22,6 +29,47 @@ type Item struct {
 	Tag         string
 }
 
+// ItemDetail struct for order item with tax and discount
+// UnitPrice is price per unit including tax, Discount is total discount for the line
+type ItemDetail struct {
+	UnitPrice   float64
+	Quantity    int8
+	TaxRate     float64
+	Discount    float64
+	ProductCode string
+	ProductName string
+	Tag         string
+}
+
+// OrderDetail struct for order with revenue breakdown
+// Gross is total paid by customer including tax and shipping after discount, Net is Gross without Tax
+// Customer is optional pseudonymous customer key, e.g. hashed email or shop customer id
+// Fingerprint is optional visitor fingerprint used for attribution, see Fingerprint
+// Channel is sales channel, web by default, Created is optional order time for imported orders
+// Items are stored with tax rate and discount, items passed to AddOrder are used when empty
+type OrderDetail struct {
+	ExternalOrderId string
+	StoreId         string
+	Currency        string
+	Tag             string
+	Channel         string
+	Customer        string
+	Fingerprint     string
+	Created         time.Time
+	Net             float64
+	Gross           float64
+	Tax             float64
+	Shipping        float64
+	Discount        float64
+	Items           []ItemDetail
+}
+
+// Revenue basis for aggregate queries, passed as "revenue" param
+const (
+	RevenueGross = "gross"
+	RevenueNet   = "net"
+)
+
 // ClientData struct store db client
 type ClientData struct {
 	db *gorm.DB
@@ -29,8 +77,12 @@ type ClientData struct {
 
 // AmountByDay struct store order value for each day
 type AmountByDay struct {
-	Value   float64
-	Updated time.Time
+	Value    float64
+	Updated  time.Time
+	Day      time.Time
+	Channel  string
+	Anomaly  bool
+	Calendar CalendarDay `gorm:"-"`
 }
 
 // VisitorsByDay struct store visitors count for each day
@@ -39,6 +91,8 @@ type VisitorsByDay struct {
 	Updated  time.Time
 	Day      time.Time
 	Tag      string
+	Anomaly  bool
+	Calendar CalendarDay `gorm:"-"`
 }
 
 // VisitorsOfflineByDay struct store visitors offline count for each day
@@ -54,6 +108,37 @@ type OrdersByDay struct {
 	Quantity int
 	Updated  time.Time
 	Day      time.Time
+	Channel  string
+	Anomaly  bool
+	Calendar CalendarDay `gorm:"-"`
+}
+
+// CustomersByDay struct store new and returning customers for each day
+type CustomersByDay struct {
+	Day                time.Time
+	NewCustomers       int
+	ReturningCustomers int
+}
+
+// PurchaseFrequency struct store repeat purchase statistics for store
+type PurchaseFrequency struct {
+	Customers         int
+	Orders            int
+	RepeatCustomers   int
+	RepeatRate        float64
+	OrdersPerCustomer float64
+	DaysBetweenOrders float64
+}
+
+// CustomerLifetimeValue struct store historical customer lifetime value for store
+type CustomerLifetimeValue struct {
+	Customers         int
+	Orders            int
+	Revenue           float64
+	AverageOrderValue float64
+	PurchaseFrequency float64
+	LifespanDays      float64
+	Value             float64
 }
 
 // TopSellProduct struct store data for top sel product
@@ -71,6 +156,8 @@ type Product struct {
 	ProductCode string
 	StoreId     string
 	Name        string
+	ParentCode  string
+	CategoryId  string
 }
 
 // ProductToStore struct store info about product need to order
@@ -101,27 +188,54 @@ func NewConnect(dsn string) ClientData {
 		&Orders{},
 		&Visitors{},
 		&Products{},
-		&ProductsToStore{})
+		&ProductsToStore{},
+		&Customers{},
+		&Attributions{},
+		&Categories{},
+		&StoreTimezones{},
+		&Forecasts{},
+		&VisitorRollups{},
+		&OrderRollups{},
+		&RollupWatermarks{},
+		&RollupDeletes{},
+		&Anomalies{},
+		&Holidays{},
+		&Promotions{},
+		&StoreCountries{})
+
+	// indexes for rollup refresh of rows ingested after watermark
+	db.Exec("CREATE INDEX IF NOT EXISTS idx_visitors_store_updated ON visitors (store_id, updated_at)")
+	db.Exec("CREATE INDEX IF NOT EXISTS idx_orders_store_updated ON orders (store_id, updated_at)")
+
+	// hard deletes are not visible by updated_at, trigger records them for next rollup refresh
+	errTrigger := db.Exec("CREATE OR REPLACE FUNCTION rollup_deleted() RETURNS trigger AS $$ BEGIN " +
+		"INSERT INTO rollup_deletes (id, created_at, updated_at, store_id, source, row_created_at) " +
+		"VALUES (uuid_generate_v4()::text, now(), now(), OLD.store_id, TG_TABLE_NAME, OLD.created_at); RETURN OLD; END $$ LANGUAGE plpgsql").Error
+	if errTrigger != nil {
+		panic(errTrigger)
+	}
+	for _, table := range []string{"visitors", "orders"} {
+		db.Exec("DROP TRIGGER IF EXISTS " + table + "_rollup_deleted ON " + table)
+		db.Exec("CREATE TRIGGER " + table + "_rollup_deleted AFTER DELETE ON " + table + " FOR EACH ROW EXECUTE PROCEDURE rollup_deleted()")
+	}
 
-	// create visitors view for prediction performance
-	errView := db.Exec("CREATE or REPLACE VIEW visitorsView AS SELECT count(*) AS visitors, store_id, date_trunc('day', created_at)::date AS day, tag FROM visitors WHERE header NOT LIKE '%Googlebot%' GROUP BY store_id, day, tag ORDER BY day").Error
+	// views keep their columns for compatibility, they read from rollups maintained by RefreshRollups
+	errView := db.Exec("CREATE or REPLACE VIEW visitorsView AS SELECT sum(visitors)::bigint AS visitors, store_id, day, tag FROM visitor_rollups GROUP BY store_id, day, tag ORDER BY day").Error
 	if errView != nil {
 		panic(errView)
 	}
 
-	// create order view for prediction performance
-	errView2 := db.Exec("CREATE or REPLACE VIEW ordersView AS SELECT count(*) AS orders, store_id, date_trunc('day', created_at)::date AS day FROM orders GROUP BY store_id, day ORDER BY day").Error
+	errView2 := db.Exec("CREATE or REPLACE VIEW ordersView AS SELECT sum(orders)::bigint AS orders, store_id, day FROM order_rollups WHERE product_code = '' GROUP BY store_id, day ORDER BY day").Error
 	if errView2 != nil {
 		panic(errView2)
 	}
 
-	// create visitors view oer product for prediction performance
-	errView3 := db.Exec("CREATE or REPLACE VIEW visitorsProductView AS SELECT count(*) AS visitors, store_id, product_code, date_trunc('day', created_at)::date AS day, tag FROM visitors WHERE product_code NOT LIKE '' GROUP BY store_id, product_code, day, tag ORDER BY day").Error
+	errView3 := db.Exec("CREATE or REPLACE VIEW visitorsProductView AS SELECT sum(visitors)::bigint AS visitors, store_id, product_code, day, tag FROM visitor_rollups WHERE product_code <> '' GROUP BY store_id, product_code, day, tag ORDER BY day").Error
 	if errView3 != nil {
 		panic(errView3)
 	}
 
-	errView4 := db.Exec("CREATE or REPLACE VIEW orderProductView AS SELECT count(order_items.*)::int AS orders, sum(order_items.quantity)::int AS quantity, store_id, product_code, date_trunc('day', order_items.created_at)::date AS day FROM order_items LEFT JOIN orders ON order_items.order = orders.id WHERE order_items.product_code NOT LIKE '' GROUP BY orders.store_id, order_items.product_code, day ORDER BY day").Error
+	errView4 := db.Exec("CREATE or REPLACE VIEW orderProductView AS SELECT sum(orders)::int AS orders, sum(quantity)::int AS quantity, store_id, product_code, day FROM order_rollups WHERE product_code <> '' GROUP BY store_id, product_code, day ORDER BY day").Error
 	if errView4 != nil {
 		panic(errView4)
 	}
@@ -131,7 +245,7 @@ func NewConnect(dsn string) ClientData {
 
 // AddVisitor function to store visitor in database
 func (client *ClientData) AddVisitor(ip string, storeId string, url string, productCode string, header string, tag string) *gorm.DB {
-	visitor := Visitors{Ip: ip, StoreId: storeId, Url: url, ProductCode: productCode, Header: header, Tag: tag}
+	visitor := Visitors{Ip: ip, StoreId: storeId, Url: url, ProductCode: productCode, Header: header, Tag: tag, Fingerprint: Fingerprint(ip, header)}
 	result := client.db.Create(&visitor)
 	return result
 }
@@ -144,8 +258,35 @@ func (client *ClientData) AddVisitorOffline(info string, storeId string) *gorm.D
 }
 
 // AddOrder function to store order in database
-func (client *ClientData) AddOrder(amount float64, currency string, storeId string, orderItems []Item, orderId string, tag string) *gorm.DB {
-	order := Orders{Amount: amount, StoreId: storeId, Currency: currency, ExternalOrderId: orderId, Tag: tag}
+// optional detail adds revenue breakdown, customer and items with tax, its empty store, currency, order id and tag are taken
+// from arguments and gross is amount when detail has neither gross nor net
+func (client *ClientData) AddOrder(amount float64, currency string, storeId string, orderItems []Item, orderId string, tag string, detail ...OrderDetail) *gorm.DB {
+	if len(detail) > 0 {
+		d := detail[0]
+		if d.StoreId == "" {
+			d.StoreId = storeId
+		}
+		if d.Currency == "" {
+			d.Currency = currency
+		}
+		if d.ExternalOrderId == "" {
+			d.ExternalOrderId = orderId
+		}
+		if d.Tag == "" {
+			d.Tag = tag
+		}
+		if d.Gross == 0 && d.Net == 0 {
+			d.Gross = amount
+		}
+		items := d.Items
+		if len(items) == 0 {
+			for _, v := range orderItems {
+				items = append(items, ItemDetail{UnitPrice: v.UnitPrice, Quantity: v.Quantity, ProductCode: v.ProductCode, ProductName: v.ProductName, Tag: v.Tag})
+			}
+		}
+		return client.AddOrderDetail(d, items)
+	}
+	order := Orders{Amount: amount, StoreId: storeId, Currency: currency, ExternalOrderId: orderId, Tag: tag, Channel: ChannelWeb}
 	result := client.db.Create(&order)
 	for _, v := range orderItems {
 		client.AddOrderItem(v, order.Id)
@@ -153,6 +294,52 @@ func (client *ClientData) AddOrder(amount float64, currency string, storeId stri
 	return result
 }
 
+// AddOrderDetail function to store order with revenue breakdown in database
+func (client *ClientData) AddOrderDetail(detail OrderDetail, orderItems []ItemDetail) *gorm.DB {
+	if detail.Gross == 0 {
+		detail.Gross = detail.Net + detail.Tax
+	}
+	if detail.Net == 0 {
+		detail.Net = detail.Gross - detail.Tax
+	}
+	if detail.Channel == "" {
+		detail.Channel = ChannelWeb
+	}
+	if detail.Created.IsZero() {
+		detail.Created = time.Now()
+	}
+	customerId := ""
+	if detail.Customer != "" {
+		customerId = client.AddCustomer(detail.StoreId, detail.Customer, detail.Created).Id
+	}
+	order := Orders{
+		Amount:          detail.Gross,
+		AmountNet:       detail.Net,
+		Tax:             detail.Tax,
+		Shipping:        detail.Shipping,
+		Discount:        detail.Discount,
+		Breakdown:       true,
+		StoreId:         detail.StoreId,
+		Currency:        detail.Currency,
+		ExternalOrderId: detail.ExternalOrderId,
+		Tag:             detail.Tag,
+		CustomerId:      customerId,
+		Fingerprint:     detail.Fingerprint,
+		Channel:         detail.Channel}
+	order.CreatedAt = detail.Created
+	result := client.db.Create(&order)
+	if result.Error != nil {
+		return result
+	}
+	for _, v := range orderItems {
+		item := orderItemDetail(v, order.Id, order.CreatedAt)
+		if itemResult := client.db.Create(&item); itemResult.Error != nil {
+			return itemResult
+		}
+	}
+	return result
+}
+
 // AddOrderItem function to store order item in database
 func (client *ClientData) AddOrderItem(o Item, orderId string) OrderItems {
 	item := OrderItems{UnitPrice: o.UnitPrice, Quantity: o.Quantity, ProductCode: o.ProductCode, Order: orderId, ProductName: o.ProductName}
@@ -160,6 +347,51 @@ func (client *ClientData) AddOrderItem(o Item, orderId string) OrderItems {
 	return item
 }
 
+// AddOrderItemDetail function to store order item with tax and discount in database
+func (client *ClientData) AddOrderItemDetail(o ItemDetail, orderId string, created time.Time) OrderItems {
+	item := orderItemDetail(o, orderId, created)
+	client.db.Create(&item)
+	return item
+}
+
+// orderItemDetail function return order item with tax and discount of order created at time
+func orderItemDetail(o ItemDetail, orderId string, created time.Time) OrderItems {
+	item := OrderItems{UnitPrice: o.UnitPrice, Quantity: o.Quantity, TaxRate: o.TaxRate, Discount: o.Discount,
+		ProductCode: o.ProductCode, Order: orderId, ProductName: o.ProductName}
+	item.CreatedAt = created
+	return item
+}
+
+// AddCustomer function to create customer by pseudonymous key or register its order time on existing one
+// concurrent orders of new customer are merged by unique store and key instead of failing on it
+func (client *ClientData) AddCustomer(storeId string, customerKey string, orderTime time.Time) Customers {
+	var customer Customers
+	client.db.Raw("INSERT INTO customers (id, created_at, updated_at, store_id, customer_key, first_order, last_order) "+
+		"VALUES (@id, now(), now(), @store_id, @customer_key, @order_time, @order_time) ON CONFLICT (store_id, customer_key) DO UPDATE SET "+
+		"first_order = LEAST(customers.first_order, excluded.first_order), last_order = GREATEST(customers.last_order, excluded.last_order), "+
+		"updated_at = now(), deleted_at = NULL RETURNING id, created_at, updated_at, store_id, customer_key, first_order, last_order",
+		map[string]interface{}{"id": uuid.New().String(), "store_id": storeId, "customer_key": customerKey, "order_time": orderTime}).Scan(&customer)
+	return customer
+}
+
+// CustomerKey function return pseudonymous customer key from email
+func CustomerKey(email string) string {
+	hash := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(email))))
+	return hex.EncodeToString(hash[:])
+}
+
+// revenueColumn function return amount expression for revenue basis in params, gross by default
+// orders stored without breakdown have only gross amount
+// it is used by all order amount aggregates taking params (GetAmountForPrediction, GetAverageOrderAmount, GetOrdersAvgByDate,
+// GetAmountSeries, GetSumRevenue and GetCustomerLifetimeValue), attributions keep both bases in their own columns,
+// GetSumOrder is always gross and GetTopSellProducts averages item unit price, not order amount
+func revenueColumn(params map[string]interface{}) string {
+	if params["revenue"] == RevenueNet {
+		return "(CASE WHEN breakdown THEN amount_net ELSE amount END)"
+	}
+	return "amount"
+}
+
 // CreateProduct function to store product in database
 func (client *ClientData) CreateProduct(productCode string, name string, quantity int8, storeId string) Products {
 	item := Products{Quantity: quantity, ProductCode: productCode, StoreId: storeId, Name: name}
@@ -172,6 +404,63 @@ func (client *ClientData) UpdateProduct(productCode string, name string, storeId
 	return client.db.Model(&Products{}).Where("product_code = ? AND store_id = ?", productCode, storeId).Updates(Products{Quantity: quantity, Name: name})
 }
 
+// UpdateProductHierarchy function to set product category and parent product code of variant
+func (client *ClientData) UpdateProductHierarchy(productCode string, storeId string, categoryId string, parentCode string) *gorm.DB {
+	return client.db.Model(&Products{}).Where("product_code = ? AND store_id = ?", productCode, storeId).
+		Updates(map[string]interface{}{"category_id": categoryId, "parent_code": parentCode})
+}
+
+// GetProductVariants function to return variants of parent product
+func (client *ClientData) GetProductVariants(parentCode string, storeId string) []Product {
+	var products []Product
+	client.db.Model(&Products{}).Where("parent_code = ? AND store_id = ?", parentCode, storeId).Find(&products)
+	return products
+}
+
+// CreateCategory function to store product category in database, empty parentId for root category
+func (client *ClientData) CreateCategory(storeId string, name string, code string, parentId string) Categories {
+	category := Categories{StoreId: storeId, Name: name, Code: code, ParentId: parentId}
+	client.db.Create(&category)
+	return category
+}
+
+// UpdateCategory function to update product category in database
+// parent which is the category itself or one of its descendants is rejected since it would make a cycle
+func (client *ClientData) UpdateCategory(id string, name string, code string, parentId string) (Categories, error) {
+	var category Categories
+	client.db.Model(&Categories{}).Where("id = ?", id).First(&category)
+	if parentId != "" {
+		var cycle bool
+		client.db.Raw("WITH RECURSIVE ancestors AS (SELECT id, parent_id FROM categories WHERE id = @parent_id AND deleted_at IS NULL "+
+			"UNION SELECT categories.id, categories.parent_id FROM categories JOIN ancestors ON categories.id = ancestors.parent_id WHERE categories.deleted_at IS NULL) "+
+			"SELECT EXISTS (SELECT 1 FROM ancestors WHERE id = @id)", map[string]interface{}{"id": id, "parent_id": parentId}).Scan(&cycle)
+		if cycle {
+			return category, errors.New("category " + parentId + " is category " + id + " or its descendant")
+		}
+	}
+	category.Name = name
+	category.Code = code
+	category.ParentId = parentId
+	err := client.db.Save(&category).Error
+	return category, err
+}
+
+// GetCategories function to return category tree for store as flat list
+func (client *ClientData) GetCategories(storeId string) []Categories {
+	var categories []Categories
+	client.db.Model(&Categories{}).Where("store_id = ?", storeId).Order("name").Find(&categories)
+	return categories
+}
+
+// DeleteCategory function to delete category, children and products move to its parent
+func (client *ClientData) DeleteCategory(id string) {
+	var category Categories
+	client.db.Model(&Categories{}).Where("id = ?", id).First(&category)
+	client.db.Model(&Categories{}).Where("parent_id = ?", id).Update("parent_id", category.ParentId)
+	client.db.Model(&Products{}).Where("category_id = ?", id).Update("category_id", category.ParentId)
+	client.db.Model(&Categories{}).Where("id = ?", id).Delete(&category)
+}
+
 // GetProduct function to return product by cide and store
 func (client *ClientData) GetProduct(productCode string, storeId string) Product {
 	var product Product
@@ -203,11 +492,29 @@ func (client *ClientData) GetOrders(condition map[string]interface{}, limit int,
 	return orders
 }
 
-// GetAmountForPrediction function return order amount for prediction
+// GetAmountForPrediction function return dense daily order amount for prediction, days without orders have zero value
+// optional from and to params limit series, first and last day with order of store by default
 func (client *ClientData) GetAmountForPrediction(params map[string]interface{}) []AmountByDay {
 	var result []AmountByDay
-	client.db.Raw("SELECT coalesce(SUM(amount),0) AS value, Max(created_at) FROM orders WHERE store_id = @store_id "+
-		"GROUP BY DATE_TRUNC('day',created_at) ORDER BY max(created_at)", params).Scan(&result)
+	values := map[string]interface{}{}
+	for k, v := range params {
+		values[k] = v
+	}
+	for name, fn := range map[string]string{"from": "min", "to": "max"} {
+		if _, ok := values[name]; ok {
+			continue
+		}
+		var day string
+		client.db.Raw("SELECT "+bucket(fn+"(created_at)", GranularityDay)+"::date::text FROM orders WHERE store_id = @store_id"+channelFilter(values), values).Scan(&day)
+		if day == "" {
+			return result
+		}
+		values[name] = day
+	}
+	client.db.Raw("SELECT day, coalesce(value, 0) AS value, updated FROM "+seriesBuckets(GranularityDay)+
+		" LEFT JOIN (SELECT "+bucket("created_at", GranularityDay)+" AS day, sum("+revenueColumn(values)+") AS value, max(created_at) AS updated "+
+		"FROM orders WHERE store_id = @store_id AND "+seriesCondition("created_at", GranularityDay)+channelFilter(values)+
+		" GROUP BY 1) t USING (day) ORDER BY day", values).Scan(&result)
 	return result
 }
 
@@ -215,70 +522,63 @@ func (client *ClientData) GetAmountForPrediction(params map[string]interface{})
 func (client *ClientData) GetSumOrdersForPrediction(params map[string]interface{}) float64 {
 	var result float64
 	client.db.Raw("SELECT COUNT(id) AS count FROM orders WHERE store_id = @store_id "+
-		"AND created_at < @created", params).Scan(&result)
+		"AND created_at < @created"+channelFilter(params), params).Scan(&result)
 	return result
 }
 
 // GetVisitorsForPrediction function to return visitors for prediction
 func (client *ClientData) GetVisitorsForPrediction(from string, to string, store string) []VisitorsByDay {
-	var result []VisitorsByDay
-	client.db.Raw("SELECT * FROM (SELECT day::date FROM generate_series(timestamp '" + from + "', timestamp '" + to + "', interval  '1 day') day) d LEFT JOIN (SELECT date_trunc('day', created_at)::date AS day, count(*)::int AS visitors FROM visitors WHERE  created_at >= date '" + from + "' AND  created_at <= date '" + to + "' AND store_id = '" + store + "' AND product_code = '' AND header NOT LIKE '%Googlebot%' GROUP  BY 1) t USING (day) ORDER  BY day").Scan(&result)
-	return result
+	return client.GetVisitorsSeries(map[string]interface{}{"from": from, "to": to, "store_id": store})
 }
 
-// GetVisitorsForPredictionView function to return visitors for prediction from special database view
+// GetVisitorsForPredictionView function to return dense visitors series for prediction from daily rollups
 func (client *ClientData) GetVisitorsForPredictionView(from string, to string, store string) []VisitorsByDay {
-	var result []VisitorsByDay
-	client.db.Raw("SELECT * FROM visitorsview WHERE day >= date '" + from + "' AND  day <= date '" + to + "' AND store_id = '" + store + "' ORDER BY day").Scan(&result)
-	return result
+	return client.GetVisitorsRollupSeries(map[string]interface{}{"from": from, "to": to, "store_id": store})
 }
 
 // GetOrdersForPrediction function return orders for prediction
 func (client *ClientData) GetOrdersForPrediction(from string, to string, store string) []OrdersByDay {
-	var result []OrdersByDay
-	client.db.Raw("SELECT * FROM (SELECT day::date FROM generate_series(timestamp '" + from + "', timestamp '" + to + "', interval  '1 day') day) d LEFT JOIN (SELECT date_trunc('day', created_at)::date AS day, count(*)::int AS orders FROM orders WHERE  created_at >= date '" + from + "' AND  created_at <= date '" + to + "' AND store_id = '" + store + "' GROUP  BY 1) t USING (day) ORDER  BY day").Scan(&result)
-	return result
+	return client.GetOrdersSeries(map[string]interface{}{"from": from, "to": to, "store_id": store})
 }
 
-// GetOrdersForPredictionView function return orders for prediction from special database view
+// GetOrdersForPredictionView function return dense orders series for prediction from daily rollups
 func (client *ClientData) GetOrdersForPredictionView(from string, to string, store string) []OrdersByDay {
-	var result []OrdersByDay
-	client.db.Raw("SELECT * FROM ordersview WHERE  day >= date '" + from + "' AND  day <= date '" + to + "' AND store_id = '" + store + "' ORDER  BY day").Scan(&result)
-	return result
+	return client.GetOrdersRollupSeries(map[string]interface{}{"from": from, "to": to, "store_id": store})
 }
 
 // GetAverageOrderAmount function return order amount for prediction
 func (client *ClientData) GetAverageOrderAmount(params map[string]interface{}) float64 {
 	var result float64
-	client.db.Raw("SELECT coalesce(AVG(amount),0) AS amount FROM orders WHERE store_id = @store_id", params).Scan(&result)
+	client.db.Raw("SELECT coalesce(AVG("+revenueColumn(params)+"),0) AS amount FROM orders WHERE store_id = @store_id"+channelFilter(params), params).Scan(&result)
 	return result
 }
 
 // GetOrdersCountByDate function return order count by day
+// from and to params are local time of store timezone, same for other ByDate functions
 func (client *ClientData) GetOrdersCountByDate(params map[string]interface{}) float64 {
 	var result float64
-	client.db.Raw("SELECT count(id) FROM orders WHERE created_at > @from AND created_at < @to AND store_id = @store_id", params).Scan(&result)
+	client.db.Raw("SELECT count(id) FROM orders WHERE created_at > "+localTime("CAST(@from AS timestamp)")+" AND created_at < "+localTime("CAST(@to AS timestamp)")+" AND store_id = @store_id"+channelFilter(params), params).Scan(&result)
 	return result
 }
 
 // GetOrdersCountByDatePerProduct function return order count by day per product
 func (client *ClientData) GetOrdersCountByDatePerProduct(params map[string]interface{}) float64 {
 	var result float64
-	client.db.Raw("SELECT count(orders.id) FROM orders LEFT JOIN order_items ON order_items.order = orders.id WHERE order_items.product_code = @product_code AND orders.created_at > @from AND orders.created_at < @to AND store_id = @store_id", params).Scan(&result)
+	client.db.Raw("SELECT count(orders.id) FROM orders LEFT JOIN order_items ON order_items.order = orders.id WHERE order_items.product_code = @product_code AND orders.created_at > "+localTime("CAST(@from AS timestamp)")+" AND orders.created_at < "+localTime("CAST(@to AS timestamp)")+" AND store_id = @store_id"+channelFilter(params), params).Scan(&result)
 	return result
 }
 
 // GetOrdersAvgByDate function to get average amount of orders per day
 func (client *ClientData) GetOrdersAvgByDate(params map[string]interface{}) float64 {
 	var result float64
-	client.db.Raw("SELECT coalesce(AVG(amount),0) from orders where created_at > @from AND created_at < @to AND store_id = @store_id", params).Scan(&result)
+	client.db.Raw("SELECT coalesce(AVG("+revenueColumn(params)+"),0) from orders where created_at > "+localTime("CAST(@from AS timestamp)")+" AND created_at < "+localTime("CAST(@to AS timestamp)")+" AND store_id = @store_id"+channelFilter(params), params).Scan(&result)
 	return result
 }
 
 // GetVisitorsCountByDate function to return visitors count per day
 func (client *ClientData) GetVisitorsCountByDate(params map[string]interface{}) float64 {
 	var result float64
-	client.db.Raw("SELECT count(id) from visitors where created_at > @from AND created_at < @to AND product_code = '' AND header NOT LIKE '%Googlebot%' AND store_id = @store_id", params).Scan(&result)
+	client.db.Raw("SELECT count(id) from visitors where created_at > "+localTime("CAST(@from AS timestamp)")+" AND created_at < "+localTime("CAST(@to AS timestamp)")+" AND product_code = '' AND header NOT LIKE '%Googlebot%' AND store_id = @store_id", params).Scan(&result)
 	return result
 }
 
@@ -289,39 +589,60 @@ func (client *ClientData) GetTopSellProducts(params map[string]interface{}) []To
 	return result
 }
 
-// GetFirstRecord function return first tracked record for store
+// GetFirstRecord function return first tracked record for store in store local time
 func (client *ClientData) GetFirstRecord(params map[string]interface{}) string {
 	var result string
-	client.db.Raw("SELECT created_at FROM visitors WHERE store_id = @store_id ORDER BY created_at ASC LIMIT 1", params).Scan(&result)
+	client.db.Raw("SELECT created_at AT TIME ZONE "+storeZone+" FROM visitors WHERE store_id = @store_id ORDER BY created_at ASC LIMIT 1", params).Scan(&result)
 	return result
 }
 
 // GetVisitorsForPredictionPerProduct function return visitors data for prediction per product
 func (client *ClientData) GetVisitorsForPredictionPerProduct(from string, to string, store string, productCode string) []VisitorsByDay {
-	var result []VisitorsByDay
-	client.db.Raw("SELECT * FROM (SELECT day::date FROM generate_series(timestamp '" + from + "', timestamp '" + to + "', interval  '1 day') day) d LEFT JOIN (SELECT date_trunc('day', created_at)::date AS day, count(*)::int AS visitors FROM visitors WHERE  created_at >= date '" + from + "' AND  created_at <= date '" + to + "' AND store_id = '" + store + "' AND product_code = '" + productCode + "' AND header NOT LIKE '%Googlebot%' GROUP  BY 1) t USING (day) ORDER  BY day").Scan(&result)
-	return result
+	return client.GetVisitorsSeries(map[string]interface{}{"from": from, "to": to, "store_id": store, "product_code": productCode})
 }
 
-// GetVisitorsForPredictionPerProductView function return visitors data for prediction per product for special view
+// GetVisitorsForPredictionPerProductView function return dense visitors series for prediction per product from daily rollups
 func (client *ClientData) GetVisitorsForPredictionPerProductView(from string, to string, store string, productCode string) []VisitorsByDay {
-	var result []VisitorsByDay
-	client.db.Raw("SELECT * FROM visitorsproductview WHERE  day >= date '" + from + "' AND  day <= date '" + to + "' AND store_id = '" + store + "' AND product_code = '" + productCode + "' ORDER  BY day").Scan(&result)
-	return result
+	return client.GetVisitorsRollupSeries(map[string]interface{}{"from": from, "to": to, "store_id": store, "product_code": productCode})
 }
 
 // GetOrdersForPredictionPerProduct function return orders for prediction per product
 func (client *ClientData) GetOrdersForPredictionPerProduct(from string, to string, store string, productCode string) []OrdersByDay {
-	var result []OrdersByDay
-	client.db.Raw("SELECT * FROM (SELECT day::date FROM generate_series(timestamp '" + from + "',timestamp '" + to + "', interval  '1 day') day) d LEFT JOIN (SELECT date_trunc('day', created_at)::date AS day, count(order_items.*)::int AS orders, sum(order_items.quantity)::int AS quantity FROM order_items WHERE order_items.created_at >= date '" + from + "' AND  order_items.created_at <= date '" + to + "' AND order_items.order IN (SELECT id FROM orders WHERE orders.store_id = '" + store + "') AND order_items.product_code = '" + productCode + "' GROUP  BY 1) t USING (day) ORDER  BY day").Scan(&result)
-	return result
+	return client.GetOrdersSeries(map[string]interface{}{"from": from, "to": to, "store_id": store, "product_code": productCode})
 }
 
-// GetOrdersForPredictionPerProductView function return orders for prediction per product for special view
+// GetOrdersForPredictionPerProductView function return dense orders series for prediction per product from daily rollups
 func (client *ClientData) GetOrdersForPredictionPerProductView(from string, to string, store string, productCode string) []OrdersByDay {
-	var result []OrdersByDay
-	client.db.Raw("SELECT * FROM orderproductview WHERE day >= date '" + from + "' AND  day <= date '" + to + "' AND store_id = '" + store + "' AND product_code = '" + productCode + "' ORDER  BY day").Scan(&result)
-	return result
+	return client.GetOrdersRollupSeries(map[string]interface{}{"from": from, "to": to, "store_id": store, "product_code": productCode})
+}
+
+// categoryProducts subquery of product codes in category subtree including variants of its products
+const categoryProducts = "WITH RECURSIVE tree AS (SELECT id FROM categories WHERE id = @category_id AND store_id = @store_id AND deleted_at IS NULL " +
+	"UNION SELECT categories.id FROM categories JOIN tree ON categories.parent_id = tree.id WHERE categories.deleted_at IS NULL) " +
+	"SELECT product_code FROM products WHERE store_id = @store_id AND (category_id IN (SELECT id FROM tree) " +
+	"OR parent_code IN (SELECT product_code FROM products WHERE store_id = @store_id AND category_id IN (SELECT id FROM tree)))"
+
+// parentProducts subquery of parent product code and codes of its variants
+const parentProducts = "SELECT CAST(@parent_code AS text) UNION SELECT product_code FROM products WHERE store_id = @store_id AND parent_code = @parent_code"
+
+// GetVisitorsForPredictionPerCategory function return visitors data for prediction per category including subcategories and variants
+func (client *ClientData) GetVisitorsForPredictionPerCategory(from string, to string, store string, categoryId string) []VisitorsByDay {
+	return client.GetVisitorsSeries(map[string]interface{}{"from": from, "to": to, "store_id": store, "category_id": categoryId})
+}
+
+// GetOrdersForPredictionPerCategory function return orders for prediction per category including subcategories and variants
+func (client *ClientData) GetOrdersForPredictionPerCategory(from string, to string, store string, categoryId string) []OrdersByDay {
+	return client.GetOrdersSeries(map[string]interface{}{"from": from, "to": to, "store_id": store, "category_id": categoryId})
+}
+
+// GetVisitorsForPredictionPerParent function return visitors data for prediction per parent product summed over its variants
+func (client *ClientData) GetVisitorsForPredictionPerParent(from string, to string, store string, parentCode string) []VisitorsByDay {
+	return client.GetVisitorsSeries(map[string]interface{}{"from": from, "to": to, "store_id": store, "parent_code": parentCode})
+}
+
+// GetOrdersForPredictionPerParent function return orders for prediction per parent product summed over its variants
+func (client *ClientData) GetOrdersForPredictionPerParent(from string, to string, store string, parentCode string) []OrdersByDay {
+	return client.GetOrdersSeries(map[string]interface{}{"from": from, "to": to, "store_id": store, "parent_code": parentCode})
 }
 
 // GetSumVisitors function get sum of visitors for store
@@ -333,8 +654,14 @@ func (client *ClientData) GetSumVisitors(storeId string) float64 {
 
 // GetSumOrder function to return sum orders for store
 func (client *ClientData) GetSumOrder(storeId string) float64 {
+	return client.GetSumRevenue(storeId, RevenueGross)
+}
+
+// GetSumRevenue function to return sum of net or gross revenue for store
+func (client *ClientData) GetSumRevenue(storeId string, revenue string) float64 {
 	var result float64
-	client.db.Raw("SELECT SUM(amount) FROM orders WHERE store_id = @store_id", map[string]interface{}{"store_id": storeId}).Scan(&result)
+	params := map[string]interface{}{"store_id": storeId, "revenue": revenue}
+	client.db.Raw("SELECT SUM("+revenueColumn(params)+") FROM orders WHERE store_id = @store_id", params).Scan(&result)
 	return result
 }
 
@@ -345,17 +672,47 @@ func (client *ClientData) GetNumberOrder(storeId string) float64 {
 	return result
 }
 
-// GetPredictionR2 function return suucess of prediction
+// GetPredictionR2 function return suucess of prediction as R2 of store orders forecasts over default window
 func (client *ClientData) GetPredictionR2(storeId string) float64 {
-	var result float64
-	result = 0.92
-	return result
-}
+	return client.GetPredictionR2Window(storeId, DefaultAccuracyWindow)
+}
+
+// storeDataTables tables with store id column, order items are deleted with their orders
+// rollups are deleted after visitors and orders since deleting them records rollup deletes
+var storeDataTables = append([]string{"orders", "visitors", "customers", "attributions", "categories", "store_timezones",
+	"forecasts", "anomalies", "promotions", "store_countries"}, rollupTables...)
+
+// DeleteStoreData function to delete store data for store in one transaction
+// error is returned when delete fails or some rows of store remain after it
+func (client *ClientData) DeleteStoreData(storeId string) error {
+	params := map[string]interface{}{"store_id": storeId}
+	err := client.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM order_items WHERE \"order\" IN (SELECT id FROM orders WHERE store_id = @store_id)", params).Error; err != nil {
+			return err
+		}
+		for _, table := range storeDataTables {
+			if err := tx.Exec("DELETE FROM "+table+" WHERE store_id = @store_id", params).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
 
-// DeleteStoreData function to delete store data for store
-func (client *ClientData) DeleteStoreData(storeId string) {
-	client.db.Raw("DELETE FROM orders WHERE store_id = @store_id ", map[string]interface{}{"store_id": storeId})
-	client.db.Raw("DELETE FROM visitors WHERE store_id = @store_id ", map[string]interface{}{"store_id": storeId})
+	counts := make([]string, len(storeDataTables))
+	for i, table := range storeDataTables {
+		counts[i] = "(SELECT count(*) FROM " + table + " WHERE store_id = @store_id)"
+	}
+	var remaining int64
+	if err := client.db.Raw("SELECT "+strings.Join(counts, " + "), params).Scan(&remaining).Error; err != nil {
+		return err
+	}
+	if remaining > 0 {
+		return fmt.Errorf("%d rows of store %s remain after delete", remaining, storeId)
+	}
+	return nil
 }
 
 // DeleteStoreDataByAccountId function to delete store data for account
@@ -396,3 +753,81 @@ func (client *ClientData) GetOrderWithProduct(productCode string, storeId string
 	client.db.Raw("SELECT orders.* FROM orders LEFT JOIN order_items ON orders.id = order_items.order WHERE order_items.product_code = @product_code AND orders.store_id = @store_id", map[string]interface{}{"product_code": productCode, "store_id": storeId}).Scan(&result)
 	return result
 }
+
+// GetCustomersByDay function return new and returning customers per day, customer is new on local day of first order
+// and returning on later days, so more orders on first day do not count customer as returning
+func (client *ClientData) GetCustomersByDay(from string, to string, store string) []CustomersByDay {
+	var result []CustomersByDay
+	client.db.Raw("SELECT day, coalesce(new_customers, 0) AS new_customers, coalesce(returning_customers, 0) AS returning_customers FROM "+seriesBuckets(GranularityDay)+
+		" LEFT JOIN (SELECT o.day, count(DISTINCT o.customer_id) FILTER (WHERE o.day = f.first_day)::int AS new_customers, "+
+		"count(DISTINCT o.customer_id) FILTER (WHERE o.day > f.first_day)::int AS returning_customers "+
+		"FROM (SELECT "+bucket("orders.created_at", GranularityDay)+" AS day, customer_id FROM orders WHERE orders.store_id = @store_id AND customer_id <> '' AND "+
+		seriesCondition("orders.created_at", GranularityDay)+") o "+
+		"JOIN (SELECT customer_id, min("+bucket("created_at", GranularityDay)+") AS first_day FROM orders WHERE store_id = @store_id AND customer_id <> '' GROUP BY customer_id) f USING (customer_id) "+
+		"GROUP BY o.day) t USING (day) ORDER BY day",
+		map[string]interface{}{"from": from, "to": to, "store_id": store}).Scan(&result)
+	return result
+}
+
+// GetPurchaseFrequency function return repeat purchase statistics for store
+func (client *ClientData) GetPurchaseFrequency(storeId string) PurchaseFrequency {
+	var result PurchaseFrequency
+	client.db.Raw("SELECT count(*)::int AS customers, coalesce(sum(orders), 0)::int AS orders, count(*) FILTER (WHERE orders > 1)::int AS repeat_customers, "+
+		"coalesce(sum(span) / nullif(sum(orders - 1), 0), 0) AS days_between_orders FROM (SELECT customer_id, count(*) AS orders, "+
+		"extract(epoch FROM max(created_at) - min(created_at)) / 86400 AS span FROM orders WHERE store_id = @store_id AND customer_id <> '' GROUP BY customer_id) c",
+		map[string]interface{}{"store_id": storeId}).Scan(&result)
+	if result.Customers > 0 {
+		result.RepeatRate = float64(result.RepeatCustomers) / float64(result.Customers)
+		result.OrdersPerCustomer = float64(result.Orders) / float64(result.Customers)
+	}
+	return result
+}
+
+// GetCustomerLifetimeValue function return historical customer lifetime value for store
+// params store_id and optional revenue basis
+func (client *ClientData) GetCustomerLifetimeValue(params map[string]interface{}) CustomerLifetimeValue {
+	var result CustomerLifetimeValue
+	client.db.Raw("SELECT count(*)::int AS customers, coalesce(sum(orders), 0)::int AS orders, coalesce(sum(revenue), 0) AS revenue, coalesce(avg(span), 0) AS lifespan_days "+
+		"FROM (SELECT customer_id, count(*) AS orders, sum("+revenueColumn(params)+") AS revenue, extract(epoch FROM max(created_at) - min(created_at)) / 86400 AS span "+
+		"FROM orders WHERE store_id = @store_id AND customer_id <> '' GROUP BY customer_id) c", params).Scan(&result)
+	if result.Orders > 0 {
+		result.AverageOrderValue = result.Revenue / float64(result.Orders)
+	}
+	if result.Customers > 0 {
+		result.PurchaseFrequency = float64(result.Orders) / float64(result.Customers)
+		result.Value = result.Revenue / float64(result.Customers)
+	}
+	return result
+}
+
+// StoreLocation function return location of store timezone, database timezone when store has none
+func (client *ClientData) StoreLocation(storeId string) (*time.Location, error) {
+	var name string
+	if err := client.db.Raw("SELECT "+storeZone, map[string]interface{}{"store_id": storeId}).Scan(&name).Error; err != nil {
+		return nil, err
+	}
+	return time.LoadLocation(name)
+}
+
+// SetStoreTimezone function to set store timezone used for bucketing and rebuild stored aggregates when it changes
+func (client *ClientData) SetStoreTimezone(storeId string, timezone string) {
+	var storeTimezone StoreTimezones
+	client.db.Model(&StoreTimezones{}).Where("store_id = ?", storeId).Find(&storeTimezone)
+	if storeTimezone.Id == "" {
+		storeTimezone = StoreTimezones{StoreId: storeId, Timezone: timezone}
+		client.db.Create(&storeTimezone)
+	} else if storeTimezone.Timezone != timezone {
+		client.db.Model(&StoreTimezones{}).Where("id = ?", storeTimezone.Id).Update("timezone", timezone)
+	} else {
+		return
+	}
+	client.RebuildStoreAggregates(storeId)
+}
+
+// RebuildStoreAggregates function to recompute stored day aggregates of store after timezone change
+func (client *ClientData) RebuildStoreAggregates(storeId string) {
+	params := map[string]interface{}{"store_id": storeId}
+	client.db.Exec("UPDATE attributions SET day = "+bucket("orders.created_at", GranularityDay)+"::date FROM orders "+
+		"WHERE attributions.order_id = orders.id AND attributions.store_id = @store_id", params)
+	client.RebuildRollups(storeId)
+}
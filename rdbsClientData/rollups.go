@@ -0,0 +1,165 @@
+package rdbsClientData
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// rollupOverlap time subtracted from watermark to cover rows committed during previous refresh
+const rollupOverlap = 5 * time.Minute
+
+// RefreshRollups function to update daily rollups of store from raw rows ingested after watermark
+// all days from first changed day are recomputed, so late rows, imports and deleted rows are counted
+func (client *ClientData) RefreshRollups(storeId string) {
+	var watermark RollupWatermarks
+	client.db.Model(&RollupWatermarks{}).Where("store_id = ?", storeId).Find(&watermark)
+	refreshed := time.Now()
+	since := time.Time{}
+	if !watermark.RefreshedAt.IsZero() {
+		since = watermark.RefreshedAt.Add(-rollupOverlap)
+	}
+	if client.refreshRollups(storeId, since) == nil {
+		client.saveWatermark(storeId, refreshed)
+	}
+}
+
+// RebuildRollups function to recompute all daily rollups of store from raw data
+func (client *ClientData) RebuildRollups(storeId string) {
+	refreshed := time.Now()
+	if client.refreshRollups(storeId, time.Time{}) == nil {
+		client.saveWatermark(storeId, refreshed)
+	}
+}
+
+// saveWatermark function to store time of last rollup refresh of store
+func (client *ClientData) saveWatermark(storeId string, refreshed time.Time) {
+	var watermark RollupWatermarks
+	client.db.Model(&RollupWatermarks{}).Where("store_id = ?", storeId).Find(&watermark)
+	if watermark.Id == "" {
+		client.db.Create(&RollupWatermarks{StoreId: storeId, RefreshedAt: refreshed})
+	} else {
+		client.db.Model(&RollupWatermarks{}).Where("id = ?", watermark.Id).Update("refreshed_at", refreshed)
+	}
+}
+
+// changedFrom function return first local day of table rows updated or soft deleted after since
+// or hard deleted since last refresh, records of hard deleted rows are consumed
+func changedFrom(tx *gorm.DB, table string, params map[string]interface{}) string {
+	var from string
+	tx.Raw("WITH deleted AS (DELETE FROM rollup_deletes WHERE store_id = @store_id AND source = '"+table+"' RETURNING row_created_at AS created_at) "+
+		"SELECT "+bucket("min(created_at)", GranularityDay)+"::date::text FROM ("+
+		"SELECT created_at FROM "+table+" WHERE store_id = @store_id AND (updated_at > @since OR deleted_at > @since) "+
+		"UNION ALL SELECT created_at FROM deleted) changed", params).Scan(&from)
+	return from
+}
+
+// refreshRollups function to recompute visitor and order rollups from first changed day
+// rollups are replaced in transaction, so readers never see days deleted and not yet inserted
+func (client *ClientData) refreshRollups(storeId string, since time.Time) error {
+	return client.db.Transaction(func(tx *gorm.DB) error {
+		params := map[string]interface{}{"store_id": storeId, "since": since}
+
+		if from := changedFrom(tx, "visitors", params); from != "" {
+			params["from"] = from
+			if err := tx.Exec("DELETE FROM visitor_rollups WHERE store_id = @store_id AND day >= CAST(@from AS date)", params).Error; err != nil {
+				return err
+			}
+			if err := tx.Exec("INSERT INTO visitor_rollups (id, created_at, updated_at, store_id, product_code, day, tag, visitors) "+
+				"SELECT uuid_generate_v4()::text, now(), now(), store_id, coalesce(product_code, ''), "+bucket("created_at", GranularityDay)+"::date, coalesce(tag, ''), count(*) "+
+				"FROM visitors WHERE store_id = @store_id AND created_at >= "+localTime("CAST(@from AS timestamp)")+" AND deleted_at IS NULL AND header NOT LIKE '%Googlebot%' "+
+				"GROUP BY store_id, 5, 6, 7", params).Error; err != nil {
+				return err
+			}
+		}
+
+		if from := changedFrom(tx, "orders", params); from != "" {
+			params["from"] = from
+			if err := tx.Exec("DELETE FROM order_rollups WHERE store_id = @store_id AND day >= CAST(@from AS date)", params).Error; err != nil {
+				return err
+			}
+			// store totals with empty product code
+			if err := tx.Exec("INSERT INTO order_rollups (id, created_at, updated_at, store_id, product_code, day, tag, channel, orders, quantity, amount, amount_net) "+
+				"SELECT uuid_generate_v4()::text, now(), now(), store_id, '', day, tag, channel, count(*), sum(quantity), sum(amount), sum(amount_net) FROM ("+
+				"SELECT orders.store_id, "+bucket("orders.created_at", GranularityDay)+"::date AS day, coalesce(orders.tag, '') AS tag, coalesce(orders.channel, '"+ChannelWeb+"') AS channel, "+
+				"(SELECT coalesce(sum(quantity), 0) FROM order_items WHERE order_items.order = orders.id AND order_items.deleted_at IS NULL) AS quantity, orders.amount, "+
+				"CASE WHEN orders.breakdown THEN orders.amount_net ELSE orders.amount END AS amount_net "+
+				"FROM orders WHERE orders.store_id = @store_id AND orders.created_at >= "+localTime("CAST(@from AS timestamp)")+" AND orders.deleted_at IS NULL) o "+
+				"GROUP BY store_id, day, tag, channel", params).Error; err != nil {
+				return err
+			}
+			// per product rows
+			if err := tx.Exec("INSERT INTO order_rollups (id, created_at, updated_at, store_id, product_code, day, tag, channel, orders, quantity, amount, amount_net) "+
+				"SELECT uuid_generate_v4()::text, now(), now(), orders.store_id, order_items.product_code, "+bucket("orders.created_at", GranularityDay)+"::date, "+
+				"coalesce(orders.tag, ''), coalesce(orders.channel, '"+ChannelWeb+"'), count(DISTINCT orders.id), sum(order_items.quantity), "+
+				"sum(order_items.unit_price * order_items.quantity - coalesce(order_items.discount, 0)), "+
+				"sum((order_items.unit_price * order_items.quantity - coalesce(order_items.discount, 0)) / (1 + coalesce(order_items.tax_rate, 0) / 100)) "+
+				"FROM orders JOIN order_items ON order_items.order = orders.id WHERE orders.store_id = @store_id AND order_items.product_code <> '' "+
+				"AND orders.created_at >= "+localTime("CAST(@from AS timestamp)")+" AND orders.deleted_at IS NULL AND order_items.deleted_at IS NULL "+
+				"GROUP BY orders.store_id, 5, 6, 7, 8", params).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// rollupTables tables of store rollups, watermark and recorded deletes
+var rollupTables = []string{"visitor_rollups", "order_rollups", "rollup_watermarks", "rollup_deletes"}
+
+// DeleteRollups function to delete rollups and watermark of store
+func (client *ClientData) DeleteRollups(storeId string) {
+	params := map[string]interface{}{"store_id": storeId}
+	for _, table := range rollupTables {
+		client.db.Exec("DELETE FROM "+table+" WHERE store_id = @store_id", params)
+	}
+}
+
+// rollupCondition function return condition on rollup day covering whole buckets between from and to params
+func rollupCondition(g string) string {
+	return "day >= date_trunc('" + g + "', CAST(@from AS timestamp)) AND day < " + seriesEnd(g) + " + interval '1 " + g + "'"
+}
+
+// GetVisitorsRollupSeries function return dense visitors series like GetVisitorsSeries read from daily rollups
+// hourly series are not kept in rollups and are read from raw visitors
+func (client *ClientData) GetVisitorsRollupSeries(params map[string]interface{}) []VisitorsByDay {
+	g := granularity(params)
+	if g == GranularityHour {
+		return client.GetVisitorsSeries(params)
+	}
+	var result []VisitorsByDay
+	condition := "store_id = @store_id AND " + rollupCondition(g) + " AND " + visitorsProducts(params)
+	tag, tags := tagSplit(params, "tag", "SELECT DISTINCT tag FROM visitor_rollups WHERE "+condition)
+	client.db.Raw("SELECT day, coalesce(tag, '') AS tag, coalesce(visitors, 0) AS visitors FROM "+seriesBuckets(g)+tags+
+		" LEFT JOIN (SELECT date_trunc('"+g+"', day::timestamp) AS day, "+tag+" AS tag, sum(visitors)::int AS visitors "+
+		"FROM visitor_rollups WHERE "+condition+" GROUP BY 1, 2) t USING (day"+usingSplit(tags, "tag")+") ORDER BY day, tag", params).Scan(&result)
+	client.applyVisitorsAnomalies(params, result)
+	client.annotateVisitors(params, result)
+	return result
+}
+
+// GetOrdersRollupSeries function return dense orders series like GetOrdersSeries read from daily rollups
+// rollups table is aliased as orders so channel params work the same way, hourly series are read from raw orders
+// rollups count distinct orders per product only, so series of category or parent product with variants are read from raw orders
+// to count order with several of their products once
+func (client *ClientData) GetOrdersRollupSeries(params map[string]interface{}) []OrdersByDay {
+	g := granularity(params)
+	_, category := params["category_id"]
+	_, parent := params["parent_code"]
+	if g == GranularityHour || category || parent {
+		return client.GetOrdersSeries(params)
+	}
+	var result []OrdersByDay
+	products := "orders.product_code = ''"
+	if filter := productFilter(params); filter != "" {
+		products = "orders.product_code IN (" + filter + ")"
+	}
+	channel, channels := channelSplit(params)
+	client.db.Raw("SELECT day, coalesce(channel, '') AS channel, coalesce(orders, 0) AS orders, coalesce(quantity, 0) AS quantity FROM "+seriesBuckets(g)+channels+
+		" LEFT JOIN (SELECT date_trunc('"+g+"', orders.day::timestamp) AS day, "+channel+" AS channel, sum(orders.orders)::int AS orders, "+
+		"sum(orders.quantity)::int AS quantity FROM order_rollups orders WHERE orders.store_id = @store_id AND "+rollupCondition(g)+
+		" AND "+products+channelFilter(params)+" GROUP BY 1, 2) t USING (day"+usingSplit(channels, "channel")+") ORDER BY day, channel", params).Scan(&result)
+	client.applyOrdersAnomalies(params, result)
+	client.annotateOrders(params, result)
+	return result
+}
@@ -0,0 +1,177 @@
+package rdbsClientData
+
+// Sales channels of orders, passed as "channel" param to filter order and amount series
+const (
+	ChannelWeb         = "web"
+	ChannelPos         = "pos"
+	ChannelMarketplace = "marketplace"
+)
+
+// Time granularity of series, passed as "granularity" param, weeks are ISO weeks starting on Monday
+const (
+	GranularityHour  = "hour"
+	GranularityDay   = "day"
+	GranularityWeek  = "week"
+	GranularityMonth = "month"
+)
+
+// granularity function return series granularity from params, day by default
+func granularity(params map[string]interface{}) string {
+	switch g := params["granularity"]; g {
+	case GranularityHour, GranularityWeek, GranularityMonth:
+		return g.(string)
+	}
+	return GranularityDay
+}
+
+// storeZone expression of store timezone, database timezone for store without timezone
+const storeZone = "coalesce((SELECT nullif(timezone, '') FROM store_timezones WHERE store_id = @store_id), current_setting('TimeZone'))"
+
+// bucket function return expression truncating time column to granularity bucket in store local time
+func bucket(column string, g string) string {
+	return "date_trunc('" + g + "', " + column + " AT TIME ZONE " + storeZone + ")"
+}
+
+// localTime function return expression converting store local timestamp to point in time
+func localTime(timestamp string) string {
+	return "(" + timestamp + ") AT TIME ZONE " + storeZone
+}
+
+// seriesEnd expression of last bucket start, to param is inclusive day
+func seriesEnd(g string) string {
+	return "date_trunc('" + g + "', CAST(@to AS date) + interval '1 day' - interval '1 microsecond')"
+}
+
+// seriesBuckets function return subquery d of all buckets of granularity between from and to params
+func seriesBuckets(g string) string {
+	return "(SELECT generate_series(date_trunc('" + g + "', CAST(@from AS timestamp)), " + seriesEnd(g) + ", interval '1 " + g + "') AS day) d"
+}
+
+// seriesCondition function return condition on time column covering whole buckets between from and to params
+// from and to are local days of store timezone
+func seriesCondition(column string, g string) string {
+	return column + " >= " + localTime("date_trunc('"+g+"', CAST(@from AS timestamp))") + " AND " + column + " < " + localTime(seriesEnd(g)+" + interval '1 "+g+"'")
+}
+
+// channelFilter function return orders condition for channel in params, all channels by default
+func channelFilter(params map[string]interface{}) string {
+	if channel, ok := params["channel"].(string); ok && channel != "" {
+		return " AND orders.channel = @channel"
+	}
+	return ""
+}
+
+// productFilter function return subquery of product codes for product_code, category_id or parent_code in params
+func productFilter(params map[string]interface{}) string {
+	if _, ok := params["product_code"]; ok {
+		return "SELECT CAST(@product_code AS text)"
+	}
+	if _, ok := params["category_id"]; ok {
+		return categoryProducts
+	}
+	if _, ok := params["parent_code"]; ok {
+		return parentProducts
+	}
+	return ""
+}
+
+// channelSplit function return channel column and channels subquery when params split series by channel
+func channelSplit(params map[string]interface{}) (string, string) {
+	if params["split"] == "channel" {
+		return "orders.channel", " CROSS JOIN (SELECT DISTINCT channel FROM orders WHERE store_id = @store_id" + channelFilter(params) + ") c"
+	}
+	return "''", ""
+}
+
+// tagSplit function return tag column and tags subquery when params split series by tag
+// tags is query of distinct tags in series range, without split all tags are aggregated
+func tagSplit(params map[string]interface{}, column string, tags string) (string, string) {
+	if params["split"] == "tag" {
+		return column, " CROSS JOIN (" + tags + ") tg"
+	}
+	return "''", ""
+}
+
+// visitorsProducts function return visitors condition for product params, store series count only visits without product code
+func visitorsProducts(params map[string]interface{}) string {
+	if filter := productFilter(params); filter != "" {
+		return "product_code IN (" + filter + ")"
+	}
+	return "product_code = ''"
+}
+
+// GetVisitorsSeries function return visitors for each bucket between from and to
+// params from, to, store_id and optional granularity, product_code, category_id or parent_code and split by tag
+// every tag gets dense series when split by tag, otherwise tags are aggregated
+func (client *ClientData) GetVisitorsSeries(params map[string]interface{}) []VisitorsByDay {
+	var result []VisitorsByDay
+	g := granularity(params)
+	condition := "store_id = @store_id AND " + seriesCondition("created_at", g) + " AND " + visitorsProducts(params) + " AND header NOT LIKE '%Googlebot%'"
+	tag, tags := tagSplit(params, "coalesce(tag, '')", "SELECT DISTINCT coalesce(tag, '') AS tag FROM visitors WHERE "+condition)
+	client.db.Raw("SELECT day, coalesce(tag, '') AS tag, coalesce(visitors, 0) AS visitors FROM "+seriesBuckets(g)+tags+
+		" LEFT JOIN (SELECT "+bucket("created_at", g)+" AS day, "+tag+" AS tag, count(*)::int AS visitors "+
+		"FROM visitors WHERE "+condition+" GROUP BY 1, 2) t USING (day"+usingSplit(tags, "tag")+") ORDER BY day, tag", params).Scan(&result)
+	client.applyVisitorsAnomalies(params, result)
+	client.annotateVisitors(params, result)
+	return result
+}
+
+// PivotVisitorsByTag function return separate dense series for each tag of series split by tag
+func PivotVisitorsByTag(series []VisitorsByDay) map[string][]VisitorsByDay {
+	result := map[string][]VisitorsByDay{}
+	for _, v := range series {
+		result[v.Tag] = append(result[v.Tag], v)
+	}
+	return result
+}
+
+// GetOrdersSeries function return orders and quantity for each bucket between from and to
+// params from, to, store_id and optional granularity, product_code, category_id or parent_code, channel and split by channel
+func (client *ClientData) GetOrdersSeries(params map[string]interface{}) []OrdersByDay {
+	var result []OrdersByDay
+	g := granularity(params)
+	items := "LEFT JOIN order_items ON order_items.order = orders.id"
+	if products := productFilter(params); products != "" {
+		items = "JOIN order_items ON order_items.order = orders.id AND order_items.product_code IN (" + products + ")"
+	}
+	channel, channels := channelSplit(params)
+	client.db.Raw("SELECT day, coalesce(channel, '') AS channel, coalesce(orders, 0) AS orders, coalesce(quantity, 0) AS quantity FROM "+seriesBuckets(g)+channels+
+		" LEFT JOIN (SELECT "+bucket("orders.created_at", g)+" AS day, "+channel+" AS channel, count(DISTINCT orders.id)::int AS orders, "+
+		"coalesce(sum(order_items.quantity), 0)::int AS quantity FROM orders "+items+" WHERE orders.store_id = @store_id AND "+
+		seriesCondition("orders.created_at", g)+channelFilter(params)+" GROUP BY 1, 2) t USING (day"+usingSplit(channels, "channel")+") ORDER BY day, channel", params).Scan(&result)
+	client.applyOrdersAnomalies(params, result)
+	client.annotateOrders(params, result)
+	return result
+}
+
+// GetAmountSeries function return net or gross order amount for each bucket between from and to
+// params as GetOrdersSeries with optional revenue basis, product amount is computed from order items
+func (client *ClientData) GetAmountSeries(params map[string]interface{}) []AmountByDay {
+	var result []AmountByDay
+	g := granularity(params)
+	amount := "sum(" + revenueColumn(params) + ")"
+	items := ""
+	if products := productFilter(params); products != "" {
+		amount = "sum(order_items.unit_price * order_items.quantity - coalesce(order_items.discount, 0))"
+		if params["revenue"] == RevenueNet {
+			amount = "sum((order_items.unit_price * order_items.quantity - coalesce(order_items.discount, 0)) / (1 + coalesce(order_items.tax_rate, 0) / 100))"
+		}
+		items = " JOIN order_items ON order_items.order = orders.id AND order_items.product_code IN (" + products + ")"
+	}
+	channel, channels := channelSplit(params)
+	client.db.Raw("SELECT day, coalesce(channel, '') AS channel, coalesce(value, 0) AS value FROM "+seriesBuckets(g)+channels+
+		" LEFT JOIN (SELECT "+bucket("orders.created_at", g)+" AS day, "+channel+" AS channel, "+amount+" AS value FROM orders"+items+
+		" WHERE orders.store_id = @store_id AND "+seriesCondition("orders.created_at", g)+channelFilter(params)+
+		" GROUP BY 1, 2) t USING (day"+usingSplit(channels, "channel")+") ORDER BY day, channel", params).Scan(&result)
+	client.applyAmountAnomalies(params, result)
+	client.annotateAmount(params, result)
+	return result
+}
+
+// usingSplit function return split join column when series is split by it
+func usingSplit(split string, column string) string {
+	if split != "" {
+		return ", " + column
+	}
+	return ""
+}
@@ -0,0 +1,22 @@
+package rdbsClientInfo
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type PredictionRetentions struct {
+	gorm.Model
+	Id            uuid.UUID `gorm:"primary_key; unique"`
+	StoreRefer    string    `gorm:"uniqueIndex"`
+	Store         Stores    `gorm:"foreignKey:StoreRefer"`
+	Period        string
+	KeepDays      int
+	SummaryBucket string
+	Enabled       bool
+}
+
+func (retention *PredictionRetentions) BeforeCreate(db *gorm.DB) error {
+	retention.Id = uuid.New()
+	return nil
+}
@@ -22,6 +22,8 @@ type StoreWeights struct {
 	ProbabilityWeights string
 	Shift              int
 	LongShift          int
+	Metric             string
+	MetricValue        float64
 }
 
 func (storeWages *StoreWeights) BeforeCreate(db *gorm.DB) error {
@@ -27,6 +27,7 @@ type Stores struct {
 	ShoptetAccessToken         string
 	XmlFeed                    string
 	Window                     int8
+	Timezone                   string
 }
 
 func (stores *Stores) BeforeCreate(db *gorm.DB) error {
@@ -31,6 +31,7 @@ func NewConnect(dsn string) ClientData {
 	db.AutoMigrate(
 		&OpenData{},
 		&StoreWeights{},
+		&PredictionRetentions{},
 		&Stores{},
 		&Plan{},
 		&Accounts{},
@@ -335,6 +336,15 @@ func (client *ClientData) UpdateShoptetTokenAndId(storeId string, shoptId string
 	return s
 }
 
+// SetStoreTimezone function to set IANA timezone of store
+func (client *ClientData) SetStoreTimezone(storeId string, timezone string) Stores {
+	var s Stores
+	client.db.Model(&Stores{}).Where("id = ?", storeId).First(&s)
+	s.Timezone = timezone
+	client.db.Save(&s)
+	return s
+}
+
 // DeleteStore function to delte store in db by id
 func (client *ClientData) DeleteStore(id string) {
 	var s Stores
@@ -407,6 +417,44 @@ func (client *ClientData) EditStoreWeights(storeRefer string, name string, beta
 	return storeWeights
 }
 
+// SetStoreWeightsMetric function to store metric achieved by store weights
+func (client *ClientData) SetStoreWeightsMetric(storeRefer string, metric string, value float64) StoreWeights {
+	var storeWeights StoreWeights
+	client.db.Model(&StoreWeights{}).Where("store_refer = ?", storeRefer).First(&storeWeights)
+	storeWeights.Metric = metric
+	storeWeights.MetricValue = value
+	client.db.Save(&storeWeights)
+	return storeWeights
+}
+
+// SetPredictionRetention function to create or edit downsampling of store predicted data
+// predicted points older than keepDays are summarised by period (week or month) into summaryBucket, store bucket when empty
+func (client *ClientData) SetPredictionRetention(storeRefer string, period string, keepDays int, summaryBucket string, enabled bool) PredictionRetentions {
+	var retention PredictionRetentions
+	client.db.Model(&PredictionRetentions{}).Where("store_refer = ?", storeRefer).Find(&retention)
+	retention.StoreRefer = storeRefer
+	retention.Period = period
+	retention.KeepDays = keepDays
+	retention.SummaryBucket = summaryBucket
+	retention.Enabled = enabled
+	client.db.Save(&retention)
+	return retention
+}
+
+// GetPredictionRetention function return downsampling of store predicted data, empty when store has none
+func (client *ClientData) GetPredictionRetention(storeRefer string) PredictionRetentions {
+	var retention PredictionRetentions
+	client.db.Model(&PredictionRetentions{}).Where("store_refer = ?", storeRefer).Find(&retention)
+	return retention
+}
+
+// GetPredictionRetentions function return enabled downsampling of all stores
+func (client *ClientData) GetPredictionRetentions() []PredictionRetentions {
+	var retentions []PredictionRetentions
+	client.db.Model(&PredictionRetentions{}).Where("enabled").Find(&retentions)
+	return retentions
+}
+
 // GetStoreWeights funstion return weights for store
 func (client *ClientData) GetStoreWeights(storeId string) StoreWeights {
 	var storeWeights StoreWeights
@@ -0,0 +1,18 @@
+package rdbsClientPredictedData
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type PredictedBuckets struct {
+	gorm.Model
+	Id     string `gorm:"primary_key; unique"`
+	Bucket string `gorm:"uniqueIndex"`
+	Org    string
+}
+
+func (bucket *PredictedBuckets) BeforeCreate(db *gorm.DB) error {
+	bucket.Id = uuid.New().String()
+	return nil
+}
@@ -0,0 +1,30 @@
+package rdbsClientPredictedData
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type PredictedPoints struct {
+	gorm.Model
+	Id                 string `gorm:"primary_key; unique"`
+	Bucket             string `gorm:"uniqueIndex:idx_predicted_points_run"`
+	Org                string
+	Measurement        string    `gorm:"uniqueIndex:idx_predicted_points_run"`
+	DayIndex           int       `gorm:"uniqueIndex:idx_predicted_points_run"`
+	Time               time.Time `gorm:"uniqueIndex:idx_predicted_points_run"`
+	RunId              string    `gorm:"uniqueIndex:idx_predicted_points_run"`
+	ModelName          string
+	ModelVersion       string
+	WeightsHash        string
+	Value              float64
+	AverageOrderAmount float64
+	Bounds             string `gorm:"type:jsonb;default:'{}'"`
+}
+
+func (point *PredictedPoints) BeforeCreate(db *gorm.DB) error {
+	point.Id = uuid.New().String()
+	return nil
+}
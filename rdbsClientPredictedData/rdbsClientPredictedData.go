@@ -0,0 +1,175 @@
+// Package rdbsClientPredictedData to store predicted data in postgres instead of influx
+package rdbsClientPredictedData
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/ajandera/sp_model/predictedData"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ClientData struct to store database client
+type ClientData struct {
+	db *gorm.DB
+}
+
+// NewConnect function init database connection
+func NewConnect(dsn string) ClientData {
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+
+	// Migrate the schema
+	db.AutoMigrate(&PredictedPoints{}, &PredictedBuckets{})
+	return ClientData{db}
+}
+
+// StoreData function to store predicted point, bucket is a store id
+// point with same bucket, measurement, day index and time replaces previous one like in influx
+func (client *ClientData) StoreData(measurement string, dayIndex string, value int,
+	setAverageOrderAmount float64, time time.Time, bucket string, org string) (bool, error) {
+	return client.StoreRunData(predictedData.Run{}, measurement, dayIndex, value, setAverageOrderAmount, time, bucket, org)
+}
+
+// StoreRunData function to store predicted point like StoreData, point belongs to run when run has id
+func (client *ClientData) StoreRunData(run predictedData.Run, measurement string, dayIndex string, value int,
+	setAverageOrderAmount float64, time time.Time, bucket string, org string) (bool, error) {
+	index, err := strconv.Atoi(dayIndex)
+	if err != nil {
+		return false, err
+	}
+	point := PredictedPoints{Bucket: bucket, Org: org, Measurement: measurement, DayIndex: index, Time: time,
+		RunId: run.Id, ModelName: run.Model, ModelVersion: run.ModelVersion, WeightsHash: run.WeightsHash,
+		Value: float64(value), AverageOrderAmount: setAverageOrderAmount, Bounds: "{}"}
+	result := client.upsert([]PredictedPoints{point})
+	return result.Error == nil, result.Error
+}
+
+// WritePoints function to write points to store bucket in batches and return points which were not written
+// error is nil only when all points are persisted, value is rounded like in influx
+func (client *ClientData) WritePoints(points []predictedData.PredictedPoint, bucket string, org string) ([]predictedData.FailedPoint, error) {
+	var failed []predictedData.FailedPoint
+	for start := 0; start < len(points); start += predictedData.WriteBatchSize {
+		end := start + predictedData.WriteBatchSize
+		if end > len(points) {
+			end = len(points)
+		}
+		var batch []PredictedPoints
+		for _, p := range points[start:end] {
+			batch = append(batch, PredictedPoints{Bucket: bucket, Org: org, Measurement: p.Measurement, DayIndex: p.DayIndex,
+				Time: p.Time, RunId: p.RunId, ModelName: p.Model, ModelVersion: p.ModelVersion, WeightsHash: p.WeightsHash,
+				Value: math.Round(p.Value), AverageOrderAmount: p.AverageOrderAmount, Bounds: bounds(p)})
+		}
+		if err := client.upsert(batch).Error; err != nil {
+			// write points of failed batch one by one to find the failing ones
+			for i := range batch {
+				if err := client.upsert(batch[i : i+1]).Error; err != nil {
+					failed = append(failed, predictedData.FailedPoint{Point: points[start+i], Err: err})
+				}
+			}
+		}
+	}
+	return failed, predictedData.WriteError(failed, len(points))
+}
+
+// upsert function to create points or replace points of same series and time
+func (client *ClientData) upsert(points []PredictedPoints) *gorm.DB {
+	return client.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "bucket"}, {Name: "measurement"}, {Name: "day_index"}, {Name: "time"}, {Name: "run_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value", "average_order_amount", "org", "model_name", "model_version", "weights_hash", "bounds", "updated_at"}),
+	}).Create(&points)
+}
+
+// bounds function return json of point intervals and quantiles stored with same field names as in influx
+func bounds(p predictedData.PredictedPoint) string {
+	encoded, err := json.Marshal(p.BoundFields())
+	if err != nil {
+		return "{}"
+	}
+	return string(encoded)
+}
+
+// Flush function to flush data for bucket, points are written immediately so there is nothing to flush
+func (client *ClientData) Flush(bucket string, org string) (bool, error) {
+	return true, nil
+}
+
+// GetPredictedPoints function return predicted points of store bucket from including to excluding
+// empty measurement returns points of all measurements
+func (client *ClientData) GetPredictedPoints(bucket string, measurement string, from time.Time, to time.Time, org string) ([]predictedData.PredictedPoint, error) {
+	var points []PredictedPoints
+	query := client.db.Model(&PredictedPoints{}).Where("bucket = ? AND time >= ? AND time < ?", bucket, from, to)
+	if measurement != "" {
+		query = query.Where("measurement = ?", measurement)
+	}
+	if err := query.Order("time, measurement, day_index").Find(&points).Error; err != nil {
+		return nil, err
+	}
+
+	var result []predictedData.PredictedPoint
+	for _, p := range points {
+		var fields map[string]float64
+		json.Unmarshal([]byte(p.Bounds), &fields)
+		intervals, quantiles := predictedData.ParseBounds(fields)
+		result = append(result, predictedData.PredictedPoint{
+			Measurement:        p.Measurement,
+			DayIndex:           p.DayIndex,
+			Value:              p.Value,
+			AverageOrderAmount: p.AverageOrderAmount,
+			Time:               p.Time,
+			RunId:              p.RunId,
+			Model:              p.ModelName,
+			ModelVersion:       p.ModelVersion,
+			WeightsHash:        p.WeightsHash,
+			Intervals:          intervals,
+			Quantiles:          quantiles,
+			Days:               predictedData.ParseDays(fields),
+		})
+	}
+	return result, nil
+}
+
+// ProvisionBucket function to register store bucket, bucket which is already registered is kept
+func (client *ClientData) ProvisionBucket(bucket string, org string) error {
+	return client.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "bucket"}},
+		DoUpdates: clause.AssignmentColumns([]string{"org", "deleted_at", "updated_at"}),
+	}).Create(&PredictedBuckets{Bucket: bucket, Org: org}).Error
+}
+
+// DeleteBucket function to delete predicted points and registration of store bucket
+func (client *ClientData) DeleteBucket(bucket string, org string) error {
+	return client.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("bucket = ?", bucket).Delete(&PredictedPoints{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Where("bucket = ?", bucket).Delete(&PredictedBuckets{}).Error
+	})
+}
+
+// DeletePoints function to delete points of measurement in store bucket older than before
+func (client *ClientData) DeletePoints(bucket string, measurement string, before time.Time, org string) error {
+	return client.db.Unscoped().Where("bucket = ? AND measurement = ? AND time < ?", bucket, measurement, before).Delete(&PredictedPoints{}).Error
+}
+
+// ListBuckets function return registered buckets of org and buckets with predicted points written without provisioning
+func (client *ClientData) ListBuckets(org string) ([]string, error) {
+	var result []string
+	err := client.db.Raw("SELECT bucket FROM predicted_buckets WHERE org = @org AND deleted_at IS NULL "+
+		"UNION SELECT bucket FROM predicted_points WHERE org = @org AND deleted_at IS NULL ORDER BY bucket",
+		map[string]interface{}{"org": org}).Scan(&result).Error
+	return result, err
+}
+
+// Close function to close database connection
+func (client *ClientData) Close() {
+	if db, err := client.db.DB(); err == nil {
+		db.Close()
+	}
+}
@@ -2,39 +2,177 @@
 package sp_model
 
 import (
+	"errors"
+	"io"
+	"math"
 	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/ajandera/sp_model/forecast"
 	"github.com/ajandera/sp_model/noSqlClientPredictedData"
+	"github.com/ajandera/sp_model/predictedData"
 	"github.com/ajandera/sp_model/rdbsClientData"
 	"github.com/ajandera/sp_model/rdbsClientInfo"
+	"github.com/ajandera/sp_model/rdbsClientPredictedData"
 
 	"github.com/influxdata/influxdb-client-go/v2/api"
 	"gorm.io/gorm"
 )
 
-// Repository struct to store psql database clients
+// Repository struct to store psql database clients and optional predicted data storage with its organisation
 type Repository struct {
-	cld rdbsClientData.ClientData
-	cli rdbsClientInfo.ClientData
+	cld       rdbsClientData.ClientData
+	cli       rdbsClientInfo.ClientData
+	predicted PredictionStore
+	org       string
+}
+
+// BucketReport struct store result of bucket reconciliation
+type BucketReport struct {
+	Missing  []string
+	Created  []string
+	Orphaned []string
+}
+
+// ComparisonDay struct store actual and forecast value of day with interval bounds and forecast error
+// Error is actual minus forecast and PercentageError is relative to actual, both are set only when day has actual and forecast,
+// Source is predicted for points of prediction storage and forecasts for values recorded by ForecastStore
+type ComparisonDay struct {
+	Day             time.Time
+	Actual          float64
+	HasActual       bool
+	Forecast        float64
+	HasForecast     bool
+	Intervals       []predictedData.Interval
+	Error           float64
+	PercentageError float64
+	Source          string
+	RunId           string
+}
+
+// Sources of compared forecasts
+const (
+	SourcePredicted = "predicted"
+	SourceForecasts = "forecasts"
+)
+
+// PredictionStore interface of predicted data storage, implemented by influx and postgres clients
+type PredictionStore interface {
+	StoreData(measurement string, dayIndex string, value int, setAverageOrderAmount float64, time time.Time, bucket string, org string) (bool, error)
+	StoreRunData(run predictedData.Run, measurement string, dayIndex string, value int, setAverageOrderAmount float64, time time.Time, bucket string, org string) (bool, error)
+	Flush(bucket string, org string) (bool, error)
+	WritePoints(points []predictedData.PredictedPoint, bucket string, org string) ([]predictedData.FailedPoint, error)
+	GetPredictedPoints(bucket string, measurement string, from time.Time, to time.Time, org string) ([]predictedData.PredictedPoint, error)
+	ProvisionBucket(bucket string, org string) error
+	DeleteBucket(bucket string, org string) error
+	ListBuckets(org string) ([]string, error)
+	DeletePoints(bucket string, measurement string, before time.Time, org string) error
+	Close()
+}
+
+// ImportReport struct store result of predicted data import
+// Points is number of decoded points, Unique of distinct series and time keys among them, Verified of keys read back after write
+type ImportReport struct {
+	Points   int
+	Unique   int
+	Written  int
+	Verified int
+	Failed   []predictedData.FailedPoint
+	DryRun   bool
+}
+
+// CompactionReport struct store result of store predicted data downsampling
+// raw points of Measurements older than Cutoff were summarised into Summaries points of SummaryBucket,
+// Deleted is number of raw points missing when they were read again after delete
+type CompactionReport struct {
+	StoreId       string
+	Period        string
+	Cutoff        time.Time
+	SummaryBucket string
+	Measurements  []string
+	Points        int
+	Summaries     int
+	Deleted       int
+	Failed        []predictedData.FailedPoint
+}
+
+// Default downsampling of predicted data
+const (
+	DefaultRetentionPeriod   = noSqlClientPredictedData.PeriodWeek
+	DefaultRetentionKeepDays = 90
+)
+
+// Prediction storage backends
+const (
+	BackendInflux   = "influx"
+	BackendPostgres = "postgres"
+)
+
+// PredictedDataConfig struct store configuration of predicted data storage
+// Url and Token connect influx, Url is postgres dsn for postgres backend, Retention of store buckets is zero for forever
+type PredictedDataConfig struct {
+	Backend   string
+	Url       string
+	Token     string
+	Retention time.Duration
 }
 
-// Influx struct to store influx client
+// Influx struct to store predicted data client, influx or postgres by configuration
 type Influx struct {
-	db noSqlClientPredictedData.ClientData
+	db PredictionStore
 }
 
+// errNoPredicted error of repository without predicted data storage
+var errNoPredicted = errors.New("repository has no predicted data storage")
+
+// errNoFlux error of raw flux queries on backend other than influx
+var errNoFlux = errors.New("flux queries need influx prediction backend")
+
 // ClientsInit function to connect to psql databases
 func ClientsInit(dataDsn string, clientDsn string) Repository {
 	return Repository{
-		rdbsClientData.NewConnect(dataDsn),
-		rdbsClientInfo.NewConnect(clientDsn),
+		cld: rdbsClientData.NewConnect(dataDsn),
+		cli: rdbsClientInfo.NewConnect(clientDsn),
 	}
 }
 
-// ClientPredictedDataInit function to connect to Influx
+// WithPredictedData function return repository managing store buckets in predicted data storage of organisation
+func (r Repository) WithPredictedData(i Influx, org string) Repository {
+	r.predicted = i.db
+	r.org = org
+	return r
+}
+
+// ClientPredictedDataInit function to connect to predicted data storage
+// postgres:// or postgresql:// url selects postgres backend, any other url is influx
 func ClientPredictedDataInit(url string, token string) Influx {
-	return Influx{noSqlClientPredictedData.NewConnect(url, token)}
+	config := PredictedDataConfig{Backend: BackendInflux, Url: url, Token: token}
+	if strings.HasPrefix(url, "postgres://") || strings.HasPrefix(url, "postgresql://") {
+		config.Backend = BackendPostgres
+	}
+	return ClientPredictedDataInitWithConfig(config)
+}
+
+// ClientPredictedDataInitWithConfig function to connect to predicted data storage selected by backend
+func ClientPredictedDataInitWithConfig(config PredictedDataConfig) Influx {
+	if config.Backend == BackendPostgres {
+		client := rdbsClientPredictedData.NewConnect(config.Url)
+		return Influx{&client}
+	}
+	client := noSqlClientPredictedData.NewConnect(config.Url, config.Token)
+	client.SetRetention(config.Retention)
+	return Influx{&client}
+}
+
+// influx function return influx client for flux queries
+func (i Influx) influx() (*noSqlClientPredictedData.ClientData, error) {
+	if client, ok := i.db.(*noSqlClientPredictedData.ClientData); ok {
+		return client, nil
+	}
+	return nil, errNoFlux
 }
 
 // SaveVisitor function to save Visitors
@@ -47,9 +185,15 @@ func (r Repository) SaveVisitorOffline(info string, storeId string) *gorm.DB {
 	return r.cld.AddVisitorOffline(info, storeId)
 }
 
-// SaveOrder function to save order
-func (r Repository) SaveOrder(amount float64, currency string, storeId string, orderItems []rdbsClientData.Item, orderId string, tag string) *gorm.DB {
-	return r.cld.AddOrder(amount, currency, storeId, orderItems, orderId, tag)
+// SaveOrder function to save order, optional detail adds net, gross, tax, shipping and discount breakdown,
+// customer and items with tax rate and discount
+func (r Repository) SaveOrder(amount float64, currency string, storeId string, orderItems []rdbsClientData.Item, orderId string, tag string, detail ...rdbsClientData.OrderDetail) *gorm.DB {
+	return r.cld.AddOrder(amount, currency, storeId, orderItems, orderId, tag, detail...)
+}
+
+// ImportPosReceipts function to import POS receipt csv export as orders with pos channel
+func (r Repository) ImportPosReceipts(reader io.Reader, storeId string, currency string) (int, error) {
+	return r.cld.ImportPosReceipts(reader, storeId, currency)
 }
 
 // GetVisitors function to return visitors by condition
@@ -72,14 +216,50 @@ func (r Repository) GetAmountForPrediction(params map[string]interface{}) []rdbs
 	return r.cld.GetAmountForPrediction(params)
 }
 
-// GetVisitorsForPredictionView function to return viditors day count for prediction by special view
+// GetVisitorsSeries function to return visitors per hour, day, week or month for store, product, category or parent product
+func (r Repository) GetVisitorsSeries(params map[string]interface{}) []rdbsClientData.VisitorsByDay {
+	return r.cld.GetVisitorsSeries(params)
+}
+
+// GetOrdersSeries function to return orders per hour, day, week or month filtered or split by channel, product, category or parent product
+func (r Repository) GetOrdersSeries(params map[string]interface{}) []rdbsClientData.OrdersByDay {
+	return r.cld.GetOrdersSeries(params)
+}
+
+// GetAmountSeries function to return net or gross orders amount per hour, day, week or month filtered or split by channel
+func (r Repository) GetAmountSeries(params map[string]interface{}) []rdbsClientData.AmountByDay {
+	return r.cld.GetAmountSeries(params)
+}
+
+// GetVisitorsRollupSeries function to return dense visitors series from daily rollups
+func (r Repository) GetVisitorsRollupSeries(params map[string]interface{}) []rdbsClientData.VisitorsByDay {
+	return r.cld.GetVisitorsRollupSeries(params)
+}
+
+// GetOrdersRollupSeries function to return dense orders series from daily rollups
+func (r Repository) GetOrdersRollupSeries(params map[string]interface{}) []rdbsClientData.OrdersByDay {
+	return r.cld.GetOrdersRollupSeries(params)
+}
+
+// GetVisitorsSeriesByTag function to return separate dense visitors series for each tag
+func (r Repository) GetVisitorsSeriesByTag(params map[string]interface{}) map[string][]rdbsClientData.VisitorsByDay {
+	split := map[string]interface{}{"split": "tag"}
+	for k, v := range params {
+		if k != "split" {
+			split[k] = v
+		}
+	}
+	return rdbsClientData.PivotVisitorsByTag(r.cld.GetVisitorsSeries(split))
+}
+
+// GetVisitorsForPredictionView function to return viditors day count for prediction from daily rollups
 func (r Repository) GetVisitorsForPredictionView(from string, to string, store string) []rdbsClientData.VisitorsByDay {
 	return r.cld.GetVisitorsForPredictionView(from, to, store)
 }
 
-// GetOrdersForPredictionView get orders count per day for prediction by special view
+// GetOrdersForPredictionView get orders count per day for prediction from daily rollups
 func (r Repository) GetOrdersForPredictionView(from string, to string, store string) []rdbsClientData.OrdersByDay {
-	return r.cld.GetOrdersForPrediction(from, to, store)
+	return r.cld.GetOrdersForPredictionView(from, to, store)
 }
 
 // GetVisitorsForPrediction function to return viditors day count for prediction
@@ -112,6 +292,26 @@ func (r Repository) GetOrdersForPredictionPerProductView(from string, to string,
 	return r.cld.GetOrdersForPredictionPerProductView(from, to, store, productCode)
 }
 
+// GetVisitorsForPredictionPerCategory function to count day visitors per category including subcategories and variants
+func (r Repository) GetVisitorsForPredictionPerCategory(from string, to string, store string, categoryId string) []rdbsClientData.VisitorsByDay {
+	return r.cld.GetVisitorsForPredictionPerCategory(from, to, store, categoryId)
+}
+
+// GetOrdersForPredictionPerCategory function to count orders per category per day including subcategories and variants
+func (r Repository) GetOrdersForPredictionPerCategory(from string, to string, store string, categoryId string) []rdbsClientData.OrdersByDay {
+	return r.cld.GetOrdersForPredictionPerCategory(from, to, store, categoryId)
+}
+
+// GetVisitorsForPredictionPerParent function to count day visitors per parent product over all variants
+func (r Repository) GetVisitorsForPredictionPerParent(from string, to string, store string, parentCode string) []rdbsClientData.VisitorsByDay {
+	return r.cld.GetVisitorsForPredictionPerParent(from, to, store, parentCode)
+}
+
+// GetOrdersForPredictionPerParent function to count orders per parent product per day over all variants
+func (r Repository) GetOrdersForPredictionPerParent(from string, to string, store string, parentCode string) []rdbsClientData.OrdersByDay {
+	return r.cld.GetOrdersForPredictionPerParent(from, to, store, parentCode)
+}
+
 // GetAvgAmountForPrediction average order amount for prediction
 func (r Repository) GetAvgAmountForPrediction(params map[string]interface{}) float64 {
 	return r.cld.GetAverageOrderAmount(params)
@@ -122,6 +322,38 @@ func (r Repository) GetSumOrdersForPrediction(params map[string]interface{}) flo
 	return r.cld.GetSumOrdersForPrediction(params)
 }
 
+// GetCustomersByDay function return new and returning customers per day
+func (r Repository) GetCustomersByDay(from string, to string, store string) []rdbsClientData.CustomersByDay {
+	return r.cld.GetCustomersByDay(from, to, store)
+}
+
+// GetPurchaseFrequency function return repeat purchase statistics for store
+func (r Repository) GetPurchaseFrequency(storeId string) rdbsClientData.PurchaseFrequency {
+	return r.cld.GetPurchaseFrequency(storeId)
+}
+
+// GetCustomerLifetimeValue function return historical customer lifetime value for store
+func (r Repository) GetCustomerLifetimeValue(params map[string]interface{}) rdbsClientData.CustomerLifetimeValue {
+	return r.cld.GetCustomerLifetimeValue(params)
+}
+
+// AttributeOrders function to link orders to preceding visitor hits for all attribution models and store results
+func (r Repository) AttributeOrders(from string, to string, store string, lookbackDays int) {
+	for _, model := range rdbsClientData.AttributionModels {
+		r.cld.Attribute(from, to, store, model, lookbackDays)
+	}
+}
+
+// GetAttributionByDay function return attributed conversions and revenue per tag and day
+func (r Repository) GetAttributionByDay(params map[string]interface{}) []rdbsClientData.AttributionByDay {
+	return r.cld.GetAttributionByDay(params)
+}
+
+// GetAttributionByTag function return attributed conversions and revenue per tag
+func (r Repository) GetAttributionByTag(params map[string]interface{}) []rdbsClientData.AttributionByDay {
+	return r.cld.GetAttributionByTag(params)
+}
+
 // CheckStoreCode function to check if code belongs to store request
 func (r Repository) CheckStoreCode(code string, url string) string {
 	store, err := r.cli.CheckCode(code, url)
@@ -196,15 +428,27 @@ func (r Repository) GetAccountsForPrediction() []rdbsClientInfo.Accounts {
 }
 
 // CreateStore function to create store
+// bucket of store is provisioned when repository has predicted data storage
 func (r Repository) CreateStore(countryCode string, url string, code string, accountRefer string, offline bool, shoptetId string, shoptetToken string, feed string, window int8) *gorm.DB {
-	return r.cli.CreateStore(countryCode, url, code, accountRefer, offline, shoptetId, shoptetToken, feed, window)
+	result := r.cli.CreateStore(countryCode, url, code, accountRefer, offline, shoptetId, shoptetToken, feed, window)
+	if store, ok := result.Statement.Dest.(*rdbsClientInfo.Stores); ok && result.Error == nil {
+		r.cld.SetStoreCountry(store.Id.String(), countryCode)
+		if r.predicted != nil {
+			if err := r.predicted.ProvisionBucket(store.Id.String(), r.org); err != nil {
+				result.AddError(err)
+			}
+		}
+	}
+	return result
 }
 
 // EditStore function to edit store
 func (r Repository) EditStore(id string, countryCode string, url string, maximalProductPrice float64, minimalProductPrice float64,
 	actualStorePower float64, actualCustomerSatisfaction float64, perceivedValue float64, productSell int, offline bool, feed string, window int8) rdbsClientInfo.Stores {
-	return r.cli.EditStore(id, countryCode, url, maximalProductPrice, minimalProductPrice, actualStorePower,
+	store := r.cli.EditStore(id, countryCode, url, maximalProductPrice, minimalProductPrice, actualStorePower,
 		actualCustomerSatisfaction, perceivedValue, productSell, offline, feed, window)
+	r.cld.SetStoreCountry(id, countryCode)
+	return store
 }
 
 // Update shoptet info
@@ -212,10 +456,220 @@ func (r Repository) UpdateShoptetTokenAndId(storeId string, shoptId string, toke
 	return r.cli.UpdateShoptetTokenAndId(storeId, shoptId, token)
 }
 
-// DeleteStore function to remove store
-func (r Repository) DeleteStore(id string) {
+// SetStoreTimezone function to set store IANA timezone used for day bucketing, stored aggregates are rebuilt on change
+func (r Repository) SetStoreTimezone(storeId string, timezone string) (rdbsClientInfo.Stores, error) {
+	if _, err := time.LoadLocation(timezone); err != nil {
+		return rdbsClientInfo.Stores{}, err
+	}
+	store := r.cli.SetStoreTimezone(storeId, timezone)
+	r.cld.SetStoreTimezone(storeId, timezone)
+	return store, nil
+}
+
+// SyncStoreTimezones function to copy timezones of all stores to data database
+func (r Repository) SyncStoreTimezones() {
+	for _, store := range r.cli.GetStores() {
+		if store.Timezone != "" {
+			r.cld.SetStoreTimezone(store.Id.String(), store.Timezone)
+		}
+	}
+}
+
+// RefreshRollups function to update daily rollups of store with data ingested since last refresh
+func (r Repository) RefreshRollups(storeId string) {
+	r.cld.RefreshRollups(storeId)
+}
+
+// RefreshAllRollups function to update daily rollups of all stores, to be run periodically
+func (r Repository) RefreshAllRollups() {
+	for _, store := range r.cli.GetStores() {
+		r.cld.RefreshRollups(store.Id.String())
+	}
+}
+
+// RebuildRollups function to recompute daily rollups of store from raw data
+func (r Repository) RebuildRollups(storeId string) {
+	r.cld.RebuildRollups(storeId)
+}
+
+// SyncStoreCountries function to copy countries of all stores to data database for holiday calendar
+func (r Repository) SyncStoreCountries() {
+	for _, store := range r.cli.GetStores() {
+		if store.CountryCode != "" {
+			r.cld.SetStoreCountry(store.Id.String(), store.CountryCode)
+		}
+	}
+}
+
+// DeleteStore function to remove store, error is returned when store data are not deleted completely
+func (r Repository) DeleteStore(id string) error {
 	r.cli.DeleteStore(id)
-	r.cld.DeleteStoreData(id)
+	if err := r.cld.DeleteStoreData(id); err != nil {
+		return err
+	}
+	if r.predicted != nil {
+		return r.predicted.DeleteBucket(id, r.org)
+	}
+	return nil
+}
+
+// ReconcileBuckets function to find stores without bucket and buckets without store, missing buckets are created when create is set
+// orphaned buckets are only reported because deleting them removes predicted data
+func (r Repository) ReconcileBuckets(create bool) (BucketReport, error) {
+	var report BucketReport
+	if r.predicted == nil {
+		return report, errNoPredicted
+	}
+	buckets, err := r.predicted.ListBuckets(r.org)
+	if err != nil {
+		return report, err
+	}
+	existing := map[string]bool{}
+	for _, b := range buckets {
+		existing[b] = true
+	}
+	stores := map[string]bool{}
+	for _, store := range r.cli.GetStores() {
+		id := store.Id.String()
+		stores[id] = true
+		if existing[id] {
+			continue
+		}
+		report.Missing = append(report.Missing, id)
+		if create {
+			if err := r.predicted.ProvisionBucket(id, r.org); err != nil {
+				return report, err
+			}
+			report.Created = append(report.Created, id)
+		}
+	}
+	for _, b := range buckets {
+		if !stores[b] {
+			report.Orphaned = append(report.Orphaned, b)
+		}
+	}
+	return report, nil
+}
+
+// SetPredictionRetention function to configure downsampling of store predicted data
+// period is week or month (week by default), keepDays is age of raw points in days (90 by default),
+// summaries are written to summaryBucket or to store bucket when empty
+func (r Repository) SetPredictionRetention(storeId string, period string, keepDays int, summaryBucket string, enabled bool) (rdbsClientInfo.PredictionRetentions, error) {
+	if period == "" {
+		period = DefaultRetentionPeriod
+	}
+	if period != noSqlClientPredictedData.PeriodWeek && period != noSqlClientPredictedData.PeriodMonth {
+		return rdbsClientInfo.PredictionRetentions{}, errors.New("unsupported period " + period)
+	}
+	if keepDays <= 0 {
+		keepDays = DefaultRetentionKeepDays
+	}
+	return r.cli.SetPredictionRetention(storeId, period, keepDays, summaryBucket, enabled), nil
+}
+
+// GetPredictionRetention function return downsampling of store predicted data
+func (r Repository) GetPredictionRetention(storeId string) rdbsClientInfo.PredictionRetentions {
+	return r.cli.GetPredictionRetention(storeId)
+}
+
+// CompactPredictions function to downsample predicted data of all stores with enabled retention
+// stores are compacted independently, first error is returned with reports of all stores
+func (r Repository) CompactPredictions(now time.Time, dryRun bool) ([]CompactionReport, error) {
+	var reports []CompactionReport
+	var first error
+	for _, retention := range r.cli.GetPredictionRetentions() {
+		report, err := r.compact(retention, now, dryRun)
+		reports = append(reports, report)
+		if err != nil && first == nil {
+			first = err
+		}
+	}
+	return reports, first
+}
+
+// CompactStorePredictions function to downsample predicted data of store by its retention, default retention when store has none
+func (r Repository) CompactStorePredictions(storeId string, now time.Time, dryRun bool) (CompactionReport, error) {
+	retention := r.cli.GetPredictionRetention(storeId)
+	if retention.StoreRefer == "" {
+		retention = rdbsClientInfo.PredictionRetentions{StoreRefer: storeId, Period: DefaultRetentionPeriod, KeepDays: DefaultRetentionKeepDays, Enabled: true}
+	}
+	return r.compact(retention, now, dryRun)
+}
+
+// compact function to summarise raw points older than cutoff by period and delete them once all summaries are written
+// cutoff is aligned to period start so only whole periods are compacted, dry run only reports what would be compacted
+func (r Repository) compact(retention rdbsClientInfo.PredictionRetentions, now time.Time, dryRun bool) (CompactionReport, error) {
+	cutoff := noSqlClientPredictedData.PeriodStart(now.AddDate(0, 0, -retention.KeepDays), retention.Period)
+	report := CompactionReport{StoreId: retention.StoreRefer, Period: retention.Period, Cutoff: cutoff, SummaryBucket: retention.SummaryBucket}
+	if report.SummaryBucket == "" {
+		report.SummaryBucket = retention.StoreRefer
+	}
+	if r.predicted == nil {
+		return report, errNoPredicted
+	}
+	points, err := r.predicted.GetPredictedPoints(retention.StoreRefer, "", time.Unix(0, 0), cutoff, r.org)
+	if err != nil {
+		return report, err
+	}
+	measurements := map[string]bool{}
+	for _, p := range points {
+		if !noSqlClientPredictedData.IsSummary(p.Measurement) {
+			report.Points++
+			measurements[p.Measurement] = true
+		}
+	}
+	for m := range measurements {
+		report.Measurements = append(report.Measurements, m)
+	}
+	sort.Strings(report.Measurements)
+	summaries := noSqlClientPredictedData.Downsample(points, retention.Period)
+	report.Summaries = len(summaries)
+	if dryRun || len(summaries) == 0 {
+		return report, nil
+	}
+
+	// days of periods summarised by previous compaction are replaced by late raw points of same day
+	existing := points
+	if report.SummaryBucket != retention.StoreRefer {
+		existing, err = r.summaryPoints(report.SummaryBucket, summaries[0].Time, cutoff)
+		if err != nil {
+			return report, err
+		}
+	}
+	summaries = noSqlClientPredictedData.MergeSummaries(points, existing, retention.Period)
+	if report.Failed, err = r.predicted.WritePoints(summaries, report.SummaryBucket, r.org); err != nil {
+		return report, err
+	}
+	for _, m := range report.Measurements {
+		if err := r.predicted.DeletePoints(retention.StoreRefer, m, cutoff, r.org); err != nil {
+			return report, err
+		}
+	}
+	remaining, err := r.predicted.GetPredictedPoints(retention.StoreRefer, "", time.Unix(0, 0), cutoff, r.org)
+	if err != nil {
+		return report, err
+	}
+	report.Deleted = report.Points
+	for _, p := range remaining {
+		if !noSqlClientPredictedData.IsSummary(p.Measurement) {
+			report.Deleted--
+		}
+	}
+	return report, nil
+}
+
+// summaryPoints function return points of summary bucket from including to excluding, none when bucket does not exist yet
+func (r Repository) summaryPoints(bucket string, from time.Time, to time.Time) ([]predictedData.PredictedPoint, error) {
+	buckets, err := r.predicted.ListBuckets(r.org)
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range buckets {
+		if b == bucket {
+			return r.predicted.GetPredictedPoints(bucket, "", from, to, r.org)
+		}
+	}
+	return nil, nil
 }
 
 // GetStoresByAccount function to get stores for account
@@ -304,19 +758,191 @@ func (i Influx) StoreData(measurement string, dayIndex string, value int,
 	return i.db.StoreData(measurement, dayIndex, value, setAverageOrderAmount, time, bucket, org)
 }
 
-// Flush function to flush influx data prepared to store in bucket
+// StoreRunData function to store predicted data of prediction run, point is tagged by run like points of StoreForecast
+func (i Influx) StoreRunData(run predictedData.Run, measurement string, dayIndex string, value int,
+	setAverageOrderAmount float64, time time.Time, bucket string, org string) (bool, error) {
+	return i.db.StoreRunData(run, measurement, dayIndex, value, setAverageOrderAmount, time, bucket, org)
+}
+
+// WritePoints function to write many predicted points at once, returns points which were not persisted
+func (i Influx) WritePoints(points []predictedData.PredictedPoint, bucket string, org string) ([]predictedData.FailedPoint, error) {
+	return i.db.WritePoints(points, bucket, org)
+}
+
+// StoreForecast function to store forecast points of run in bucket, day index of point is used as daysToMeasurement
+// error is nil only when all points were persisted
+func (i Influx) StoreForecast(run predictedData.Run, measurement string, points []forecast.Point, setAverageOrderAmount float64, bucket string, org string) ([]predictedData.FailedPoint, error) {
+	var predicted []predictedData.PredictedPoint
+	for _, p := range points {
+		predicted = append(predicted, predictedData.PredictedPoint{Measurement: measurement, DayIndex: p.DayIndex,
+			Value: math.Round(p.Value), AverageOrderAmount: setAverageOrderAmount, Time: p.Day,
+			Intervals: p.Intervals, Quantiles: p.Quantiles})
+	}
+	return i.db.WritePoints(run.Tag(predicted), bucket, org)
+}
+
+// GetRuns function to return prediction runs with points of measurement between from and to, oldest first
+func (i Influx) GetRuns(bucket string, measurement string, from time.Time, to time.Time, org string) ([]predictedData.Run, error) {
+	client, err := i.influx()
+	if err != nil {
+		points, err := i.db.GetPredictedPoints(bucket, measurement, from, to, org)
+		return predictedData.Runs(points), err
+	}
+	// run tags are read from value field only
+	query := noSqlClientPredictedData.NewQuery(bucket).Range(from, to).Field("value")
+	if measurement != "" {
+		query.Measurement(measurement)
+	}
+	points, err := client.GetPredictedPointsByQuery(query.Pivot(), org)
+	return predictedData.Runs(points), err
+}
+
+// runPoints function return points of runs for measurement between from and to
+// influx filters runs in query, other backends read all points and filter them
+func (i Influx) runPoints(bucket string, measurement string, runIds []string, from time.Time, to time.Time, org string) ([]predictedData.PredictedPoint, error) {
+	client, err := i.influx()
+	if err != nil {
+		points, err := i.db.GetPredictedPoints(bucket, measurement, from, to, org)
+		var result []predictedData.PredictedPoint
+		for _, runId := range runIds {
+			result = append(result, predictedData.RunPoints(points, runId)...)
+		}
+		return result, err
+	}
+	query := noSqlClientPredictedData.NewQuery(bucket).Range(from, to).Run(runIds...)
+	if measurement != "" {
+		query.Measurement(measurement)
+	}
+	return client.GetPredictedPointsByQuery(query.Pivot(), org)
+}
+
+// GetRunPoints function to return points of run for measurement between from and to
+func (i Influx) GetRunPoints(bucket string, measurement string, runId string, from time.Time, to time.Time, org string) ([]predictedData.PredictedPoint, error) {
+	return i.runPoints(bucket, measurement, []string{runId}, from, to, org)
+}
+
+// GetLatestRunPoints function to return latest run with its points for measurement between from and to
+func (i Influx) GetLatestRunPoints(bucket string, measurement string, from time.Time, to time.Time, org string) (predictedData.Run, []predictedData.PredictedPoint, error) {
+	runs, err := i.GetRuns(bucket, measurement, from, to, org)
+	if err != nil || len(runs) == 0 {
+		return predictedData.Run{}, nil, err
+	}
+	run := runs[len(runs)-1]
+	points, err := i.runPoints(bucket, measurement, []string{run.Id}, from, to, org)
+	return run, points, err
+}
+
+// DiffRuns function to compare points of two runs for measurement between from and to, positive horizon limits day index
+func (i Influx) DiffRuns(bucket string, measurement string, before string, after string, horizon int, from time.Time, to time.Time, org string) ([]predictedData.RunDiff, error) {
+	points, err := i.runPoints(bucket, measurement, []string{before, after}, from, to, org)
+	return predictedData.DiffRuns(points, before, after, horizon), err
+}
+
+// Flush function to flush influx data prepared to store in bucket, returns error of asynchronous writes of StoreData
 func (i Influx) Flush(bucket string, org string) (bool, error) {
 	return i.db.Flush(bucket, org)
 }
 
-// GetInfluxData function to returned predicted data as string
+// GetInfluxData function to returned predicted data as string, available only for influx backend
 func (i Influx) GetInfluxData(query string, org string) (string, error) {
-	return i.db.GetData(query, org)
+	client, err := i.influx()
+	if err != nil {
+		return "", err
+	}
+	return client.GetData(query, org)
 }
 
-// GetInfluxQuery function to returned predicted data as query result table
+// GetInfluxQuery function to returned predicted data as query result table, available only for influx backend
 func (i Influx) GetInfluxQuery(query string, org string) (*api.QueryTableResult, error) {
-	return i.db.GetQuery(query, org)
+	client, err := i.influx()
+	if err != nil {
+		return nil, err
+	}
+	return client.GetQuery(query, org)
+}
+
+// GetPredictedPoints function to return typed predicted points of store bucket for measurement between from and to
+func (i Influx) GetPredictedPoints(bucket string, measurement string, from time.Time, to time.Time, org string) ([]predictedData.PredictedPoint, error) {
+	return i.db.GetPredictedPoints(bucket, measurement, from, to, org)
+}
+
+// GetPredictedPointsByQuery function to return typed predicted points selected by query builder, available only for influx backend
+func (i Influx) GetPredictedPointsByQuery(query *noSqlClientPredictedData.Query, org string) ([]predictedData.PredictedPoint, error) {
+	client, err := i.influx()
+	if err != nil {
+		return nil, err
+	}
+	return client.GetPredictedPointsByQuery(query, org)
+}
+
+// ExportPredictedData function to write points of store bucket from including to excluding in format, line protocol or csv
+// returns number of exported points
+func (i Influx) ExportPredictedData(w io.Writer, format string, bucket string, from time.Time, to time.Time, org string) (int, error) {
+	points, err := i.db.GetPredictedPoints(bucket, "", from, to, org)
+	if err != nil {
+		return 0, err
+	}
+	return noSqlClientPredictedData.Encode(w, points, format)
+}
+
+// importKey struct identify imported point, points with same key replace each other on write
+type importKey struct {
+	measurement string
+	dayIndex    int
+	time        int64
+	runId       string
+}
+
+// ImportPredictedData function to write points exported by ExportPredictedData to store bucket and read them back
+// dry run only decodes and counts points, error is returned when some points were not written or not read back
+func (i Influx) ImportPredictedData(r io.Reader, format string, bucket string, org string, dryRun bool) (ImportReport, error) {
+	points, err := noSqlClientPredictedData.Decode(r, format)
+	if err != nil {
+		return ImportReport{}, err
+	}
+	report := ImportReport{Points: len(points), DryRun: dryRun}
+	keys := map[importKey]bool{}
+	var from, to time.Time
+	for _, p := range points {
+		keys[importKey{p.Measurement, p.DayIndex, p.Time.UnixNano(), p.RunId}] = true
+		if from.IsZero() || p.Time.Before(from) {
+			from = p.Time
+		}
+		if p.Time.After(to) {
+			to = p.Time
+		}
+	}
+	report.Unique = len(keys)
+	if dryRun || len(points) == 0 {
+		return report, nil
+	}
+
+	report.Failed, err = i.db.WritePoints(points, bucket, org)
+	report.Written = len(points) - len(report.Failed)
+	if err != nil {
+		return report, err
+	}
+	// range stop is excluding, so last point is read with stop after it
+	written, err := i.db.GetPredictedPoints(bucket, "", from, to.Add(time.Nanosecond), org)
+	if err != nil {
+		return report, err
+	}
+	for _, p := range written {
+		key := importKey{p.Measurement, p.DayIndex, p.Time.UnixNano(), p.RunId}
+		if keys[key] {
+			report.Verified++
+			delete(keys, key)
+		}
+	}
+	if report.Verified < report.Unique {
+		return report, errors.New(strconv.Itoa(report.Unique-report.Verified) + " of " + strconv.Itoa(report.Unique) + " imported points not read back")
+	}
+	return report, nil
+}
+
+// Close function to close predicted data client
+func (i Influx) Close() {
+	i.db.Close()
 }
 
 // GetProducts function to return products by condition
@@ -365,6 +991,11 @@ func (r Repository) GetSumOrder(storeId string) float64 {
 	return r.cld.GetSumOrder(storeId)
 }
 
+// GetSumRevenue function return sum of net or gross revenue for specific store
+func (r Repository) GetSumRevenue(storeId string, revenue string) float64 {
+	return r.cld.GetSumRevenue(storeId, revenue)
+}
+
 // GetNumberOrders function return count number of orders for specified store
 func (r Repository) GetNumberOrders(storeId string) float64 {
 	return r.cld.GetNumberOrder(storeId)
@@ -375,6 +1006,36 @@ func (r Repository) GetPredictionR2(storeId string) float64 {
 	return r.cld.GetPredictionR2(storeId)
 }
 
+// GetPredictionR2Window function return prediction success for store over last days
+func (r Repository) GetPredictionR2Window(storeId string, days int) float64 {
+	return r.cld.GetPredictionR2Window(storeId, days)
+}
+
+// SaveForecast function to save predicted value for day, product and horizon for accuracy tracking
+func (r Repository) SaveForecast(storeId string, productCode string, measurement string, day time.Time, horizon int, value float64) rdbsClientData.Forecasts {
+	return r.cld.AddForecast(storeId, productCode, measurement, day, horizon, value)
+}
+
+// UpdateForecastActuals function to join saved forecasts with actual values between from and to
+func (r Repository) UpdateForecastActuals(storeId string, from string, to string) {
+	r.cld.UpdateForecastActuals(storeId, from, to)
+}
+
+// GetForecastAccuracy function return R2, MAPE, sMAPE, RMSE and bias of forecasts by params
+func (r Repository) GetForecastAccuracy(params map[string]interface{}) rdbsClientData.ForecastAccuracy {
+	return r.cld.GetForecastAccuracy(params)
+}
+
+// GetForecastAccuracyPerProduct function return forecast error metrics for each product
+func (r Repository) GetForecastAccuracyPerProduct(params map[string]interface{}) []rdbsClientData.ForecastAccuracy {
+	return r.cld.GetForecastAccuracyPerProduct(params)
+}
+
+// GetForecastAccuracyPerHorizon function return forecast error metrics for each horizon
+func (r Repository) GetForecastAccuracyPerHorizon(params map[string]interface{}) []rdbsClientData.ForecastAccuracy {
+	return r.cld.GetForecastAccuracyPerHorizon(params)
+}
+
 // CreateProduct function to create product in database
 func (r Repository) CreateProduct(productCode string, name string, quantity int8, storeId string) rdbsClientData.Products {
 	return r.cld.CreateProduct(productCode, name, quantity, storeId)
@@ -385,6 +1046,36 @@ func (r Repository) UpdateProduct(productCode string, name string, storeId strin
 	return r.cld.UpdateProduct(productCode, name, storeId, quantity)
 }
 
+// UpdateProductHierarchy function to set product category and parent product of variant
+func (r Repository) UpdateProductHierarchy(productCode string, storeId string, categoryId string, parentCode string) *gorm.DB {
+	return r.cld.UpdateProductHierarchy(productCode, storeId, categoryId, parentCode)
+}
+
+// GetProductVariants function to return variants of parent product
+func (r Repository) GetProductVariants(parentCode string, storeId string) []rdbsClientData.Product {
+	return r.cld.GetProductVariants(parentCode, storeId)
+}
+
+// CreateCategory function to create product category, empty parentId for root category
+func (r Repository) CreateCategory(storeId string, name string, code string, parentId string) rdbsClientData.Categories {
+	return r.cld.CreateCategory(storeId, name, code, parentId)
+}
+
+// UpdateCategory function to update product category, parent making a cycle in category tree is rejected
+func (r Repository) UpdateCategory(id string, name string, code string, parentId string) (rdbsClientData.Categories, error) {
+	return r.cld.UpdateCategory(id, name, code, parentId)
+}
+
+// GetCategories function to return all categories for store
+func (r Repository) GetCategories(storeId string) []rdbsClientData.Categories {
+	return r.cld.GetCategories(storeId)
+}
+
+// DeleteCategory function to delete category
+func (r Repository) DeleteCategory(id string) {
+	r.cld.DeleteCategory(id)
+}
+
 // GetProduct function to return product by product code in specified store
 func (r Repository) GetProduct(productCode string, storeId string) rdbsClientData.Product {
 	return r.cld.GetProduct(productCode, storeId)
@@ -497,3 +1188,267 @@ func IsValidUUID(uuid string) bool {
 	r := regexp.MustCompile("^[a-fA-F0-9]{8}-[a-fA-F0-9]{4}-4[a-fA-F0-9]{3}-[8|9|aA|bB][a-fA-F0-9]{3}-[a-fA-F0-9]{12}$")
 	return r.MatchString(uuid)
 }
+
+// DetectAnomalies function to flag anomalous days of store series, see rdbsClientData.DetectAnomalies for params
+func (r Repository) DetectAnomalies(params map[string]interface{}) []rdbsClientData.Anomalies {
+	return r.cld.DetectAnomalies(params)
+}
+
+// SetAnomalyStatus function to confirm or dismiss anomaly of store day
+func (r Repository) SetAnomalyStatus(storeId string, measurement string, day time.Time, status string) (rdbsClientData.Anomalies, error) {
+	return r.cld.SetAnomalyStatus(storeId, measurement, day, status)
+}
+
+// GetAnomalies function to get anomalies of store by params
+func (r Repository) GetAnomalies(params map[string]interface{}) []rdbsClientData.Anomalies {
+	return r.cld.GetAnomalies(params)
+}
+
+// LoadHolidays function to store bundled public holidays of country for years
+func (r Repository) LoadHolidays(countryCode string, fromYear int, toYear int) (int, error) {
+	return r.cld.LoadHolidays(countryCode, fromYear, toYear)
+}
+
+// GetHolidays function to get stored holidays of country between from and to days
+func (r Repository) GetHolidays(countryCode string, from string, to string) []rdbsClientData.Holidays {
+	return r.cld.GetHolidays(countryCode, from, to)
+}
+
+// CreatePromotion function to create promotion period of store
+func (r Repository) CreatePromotion(storeId string, name string, from time.Time, to time.Time, discount float64) rdbsClientData.Promotions {
+	return r.cld.CreatePromotion(storeId, name, from, to, discount)
+}
+
+// UpdatePromotion function to update promotion period
+func (r Repository) UpdatePromotion(id string, name string, from time.Time, to time.Time, discount float64) rdbsClientData.Promotions {
+	return r.cld.UpdatePromotion(id, name, from, to, discount)
+}
+
+// GetPromotions function to get promotions of store
+func (r Repository) GetPromotions(storeId string) []rdbsClientData.Promotions {
+	return r.cld.GetPromotions(storeId)
+}
+
+// DeletePromotion function to delete promotion
+func (r Repository) DeletePromotion(id string) {
+	r.cld.DeletePromotion(id)
+}
+
+// GetCalendar function to get holiday, promotion and weekday features of store days
+func (r Repository) GetCalendar(params map[string]interface{}) []rdbsClientData.CalendarDay {
+	return r.cld.GetCalendar(params)
+}
+
+// measurementParams function return copy of series params with orders measurement by default
+func measurementParams(params map[string]interface{}) map[string]interface{} {
+	values := map[string]interface{}{"measurement": rdbsClientData.MeasurementOrders}
+	for k, v := range params {
+		values[k] = v
+	}
+	return values
+}
+
+// comparisonParams params of forecast comparison, forecasts are kept only by store, product, measurement and horizon,
+// so other series filters like channel or category would compare filtered actuals with unfiltered forecasts
+var comparisonParams = map[string]bool{"store_id": true, "from": true, "to": true, "measurement": true, "horizon": true, "product_code": true, "anomalies": true}
+
+// GetForecastComparison function return daily series of actual values merged with forecasts by params
+// params store_id, from, to, measurement (orders by default), horizon (1 by default) and optional product_code and anomalies,
+// store forecasts are read from prediction storage (latest run of day) when repository has one and recorded forecasts fill missing days,
+// product forecasts are read from recorded forecasts only since prediction storage has no product dimension
+func (r Repository) GetForecastComparison(params map[string]interface{}) ([]ComparisonDay, error) {
+	values := measurementParams(params)
+	if _, ok := values["horizon"]; !ok {
+		values["horizon"] = 1
+	}
+	for k := range values {
+		if !comparisonParams[k] {
+			return nil, errors.New("param " + k + " is not supported by forecast comparison")
+		}
+	}
+	storeId, ok := values["store_id"].(string)
+	if !ok {
+		return nil, errors.New("store_id param must be string")
+	}
+	measurement, ok := values["measurement"].(string)
+	if !ok {
+		return nil, errors.New("measurement param must be string")
+	}
+	from, err := dayParam(values, "from")
+	if err != nil {
+		return nil, err
+	}
+	to, err := dayParam(values, "to")
+	if err != nil {
+		return nil, err
+	}
+	days := map[string]*ComparisonDay{}
+	day := func(t time.Time) *ComparisonDay {
+		key := t.Format("2006-01-02")
+		if days[key] == nil {
+			d, _ := time.Parse("2006-01-02", key)
+			days[key] = &ComparisonDay{Day: d}
+		}
+		return days[key]
+	}
+
+	for _, v := range r.cld.GetMeasurementSeries(values) {
+		d := day(v.Day)
+		d.Actual, d.HasActual = v.Value, true
+	}
+
+	productCode, _ := values["product_code"].(string)
+	if r.predicted != nil && productCode == "" {
+		points, err := r.predicted.GetPredictedPoints(storeId, measurement, from, to.AddDate(0, 0, 1), r.org)
+		if err != nil {
+			return nil, err
+		}
+		horizon := toInt(values["horizon"])
+		for _, p := range points {
+			d := day(p.Time)
+			if p.DayIndex != horizon || (d.Source == SourcePredicted && p.RunId < d.RunId) {
+				continue
+			}
+			d.Forecast, d.HasForecast, d.Intervals, d.Source, d.RunId = p.Value, true, p.Intervals, SourcePredicted, p.RunId
+		}
+	}
+	for _, f := range r.cld.GetForecasts(values) {
+		if d := day(f.Day); !d.HasForecast {
+			d.Forecast, d.HasForecast, d.Source = f.Value, true, SourceForecasts
+		}
+	}
+
+	var result []ComparisonDay
+	for _, d := range days {
+		if d.HasActual && d.HasForecast {
+			d.Error = d.Actual - d.Forecast
+			if d.Actual != 0 {
+				d.PercentageError = 100 * d.Error / d.Actual
+			}
+		}
+		result = append(result, *d)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Day.Before(result[j].Day) })
+	return result, nil
+}
+
+// dayParam function return day of date param given as string starting with yyyy-mm-dd
+func dayParam(values map[string]interface{}, name string) (time.Time, error) {
+	value, ok := values[name].(string)
+	if !ok || len(value) < 10 {
+		return time.Time{}, errors.New(name + " param must be date string")
+	}
+	return time.Parse("2006-01-02", value[:10])
+}
+
+// toInt function return integer value of param given as int, int64, float64 or numeric string
+func toInt(v interface{}) int {
+	switch n := v.(type) {
+	case int:
+		return n
+	case int64:
+		return int(n)
+	case float64:
+		return int(n)
+	case string:
+		i, _ := strconv.Atoi(n)
+		return i
+	}
+	return 0
+}
+
+// ForecastStore function to forecast store series by store weights for horizon days after to param
+// params store_id, from, to, measurement (orders by default) and optional series params like product_code or anomalies
+// forecasts of daily store or product series without other filters are recorded for accuracy tracking with day index as horizon
+func (r Repository) ForecastStore(params map[string]interface{}, horizon int, method string) ([]forecast.Point, error) {
+	return r.ForecastStoreWithBounds(params, horizon, method, []float64{}, nil)
+}
+
+// ForecastStoreWithBounds function to forecast store series as ForecastStore with prediction intervals at levels
+// and quantiles at probabilities estimated from backtest errors, levels and probabilities are between 0 and 1,
+// nil levels are predictedData.DefaultIntervalLevels and empty levels return no intervals
+func (r Repository) ForecastStoreWithBounds(params map[string]interface{}, horizon int, method string, levels []float64, probabilities []float64) ([]forecast.Point, error) {
+	values := measurementParams(params)
+	storeId, ok := values["store_id"].(string)
+	if !ok {
+		return nil, errors.New("store_id param must be string")
+	}
+	measurement, ok := values["measurement"].(string)
+	if !ok {
+		return nil, errors.New("measurement param must be string")
+	}
+	weights := forecast.FromStoreWeights(r.cli.GetStoreWeights(storeId))
+	points := forecast.ForecastWithBounds(r.cld.GetMeasurementSeries(values), weights, horizon, method, levels, probabilities)
+	if !recordable(values) {
+		return points, nil
+	}
+	productCode, _ := values["product_code"].(string)
+	for _, p := range points {
+		r.cld.AddForecast(storeId, productCode, measurement, p.Day, p.DayIndex, p.Value)
+	}
+	return points, nil
+}
+
+// recordable function return if forecast of series params matches key of recorded forecasts,
+// daily series of store or product without channel, category, split or net revenue, actuals are filled for such forecasts only
+func recordable(values map[string]interface{}) bool {
+	for k, v := range values {
+		switch {
+		case comparisonParams[k]:
+		case k == "granularity" && v == rdbsClientData.GranularityDay:
+		case k == "revenue" && v == rdbsClientData.RevenueGross:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// BacktestStore function to compare forecast configurations on store history with rolling-origin cross-validation
+// params as ForecastStore, current store weights with Holt-Winters are backtested when no configuration is given
+func (r Repository) BacktestStore(params map[string]interface{}, configs []forecast.BacktestConfig, options forecast.BacktestOptions) ([]forecast.BacktestResult, error) {
+	values := measurementParams(params)
+	storeId, ok := values["store_id"].(string)
+	if !ok {
+		return nil, errors.New("store_id param must be string")
+	}
+	if len(configs) == 0 {
+		weights := forecast.FromStoreWeights(r.cli.GetStoreWeights(storeId))
+		configs = []forecast.BacktestConfig{{Name: "current", Weights: weights, Method: forecast.MethodHoltWinters}}
+	}
+	return forecast.Compare(r.cld.GetMeasurementSeries(values), configs, options), nil
+}
+
+// OptimizeStoreWeights function to search store weights with lowest backtest RMSE and persist them with the metric
+// params as ForecastStore, weights are saved only when they beat current store weights on same folds,
+// store without weights gets new weights named optimized
+func (r Repository) OptimizeStoreWeights(params map[string]interface{}, options forecast.OptimizeOptions) (forecast.OptimizeResult, bool, error) {
+	values := measurementParams(params)
+	storeId, ok := values["store_id"].(string)
+	if !ok {
+		return forecast.OptimizeResult{}, false, errors.New("store_id param must be string")
+	}
+	storeWeights := r.cli.GetStoreWeights(storeId)
+	current := forecast.FromStoreWeights(storeWeights)
+	series := r.cld.GetMeasurementSeries(values)
+
+	result := forecast.Optimize(series, current, options)
+	if result.Evaluations == 0 || math.IsInf(result.Metrics.RMSE, 1) {
+		return result, false, nil
+	}
+	baseline := forecast.Backtest(series, forecast.BacktestConfig{Weights: current, Method: options.Method}, options.Backtest)
+	if len(baseline.Folds) > 0 && baseline.Metrics.RMSE <= result.Metrics.RMSE {
+		return result, false, nil
+	}
+
+	w := forecast.ToStoreWeights(result.Weights, storeWeights)
+	if storeWeights.StoreRefer == "" {
+		if err := r.cli.CreateStoreWeights(storeId, "optimized", w.Beta, w.Gama, w.Delta, w.A, w.B, w.C, w.D, w.E, w.ProbabilityWeights, w.Shift, w.LongShift).Error; err != nil {
+			return result, false, err
+		}
+	} else {
+		r.cli.EditStoreWeights(storeId, w.Name, w.Beta, w.Gama, w.Delta, w.A, w.B, w.C, w.D, w.E, w.ProbabilityWeights, w.Shift, w.LongShift)
+	}
+	r.cli.SetStoreWeightsMetric(storeId, "rmse", result.Metrics.RMSE)
+	return result, true, nil
+}